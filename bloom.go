@@ -0,0 +1,194 @@
+package uro
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Defaults for Options.LowMemory.
+const (
+	DefaultExpectedURLs      = 10_000_000
+	DefaultFalsePositiveRate = 0.001
+)
+
+// bloomStripes is the number of locks a bloomFilter stripes its words over.
+const bloomStripes = 256
+
+// bloomFilter is a fixed-size set of string keys with false positives,
+// safe for concurrent use. Memory is about -n*ln(fp)/ln(2)^2 bits for n keys and false positive rate fp
+// (1.8 MB per million keys at 0.1%).
+type bloomFilter struct {
+	// locks are striped by word index. add holds the stripes of all its
+	// words, so one of concurrent adds of a key wins, while adds of
+	// different keys rarely wait for each other.
+	locks [bloomStripes]sync.Mutex
+	bits  []uint64
+	m     uint64 // number of bits
+	k     uint64 // number of hash functions
+}
+
+func newBloomFilter(n int, fp float64) *bloomFilter {
+	if n <= 0 {
+		n = DefaultExpectedURLs
+	}
+	if fp <= 0 || fp >= 1 {
+		fp = DefaultFalsePositiveRate
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, m/64), m: m, k: k}
+}
+
+// add inserts the key and reports whether it was not in the filter before.
+func (b *bloomFilter) add(key string) bool {
+	h1, h2 := bloomHash(key)
+
+	// Lock the stripes in ascending order to avoid deadlocks
+	var buf [32]int
+	stripes := buf[:0]
+	for i := uint64(0); i < b.k; i++ {
+		stripes = append(stripes, int((h1+i*h2)%b.m/64%bloomStripes))
+	}
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+	for _, s := range stripes {
+		b.locks[s].Lock()
+	}
+
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if atomic.OrUint64(&b.bits[word], mask)&mask == 0 {
+			added = true
+		}
+	}
+
+	for _, s := range stripes {
+		b.locks[s].Unlock()
+	}
+	return added
+}
+
+// has reports whether the key may be in the filter.
+func (b *bloomFilter) has(key string) bool {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if atomic.LoadUint64(&b.bits[bit/64])&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) reset() {
+	clear(b.bits)
+}
+
+// bloomHash returns two hashes of the key for double hashing:
+// 64-bit FNV-1a and its splitmix64 mix (odd, so that all bits are reachable).
+func bloomHash(key string) (uint64, uint64) {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	z := h + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return h, (z ^ (z >> 31)) | 1
+}
+
+// newBloom returns a filter sized from the LowMemory options.
+func (p *Processor) newBloom() *bloomFilter {
+	return newBloomFilter(p.opts.ExpectedURLs, p.opts.FalsePositiveRate)
+}
+
+// bloomDeduper is the classic strategy on Bloom filters instead of
+// urlMap, paramsSeen and patternsSeen. A false positive can only make a
+// new URL look seen, never the other way round.
+type bloomDeduper struct {
+	p        *Processor
+	paths    *bloomFilter // host + path
+	pathKeys *bloomFilter // host + path + parameter key
+	params   *bloomFilter // scoped parameter keys
+	patterns *bloomFilter // host group + pattern
+}
+
+func newBloomDeduper(p *Processor) *bloomDeduper {
+	return &bloomDeduper{
+		p:        p,
+		paths:    p.newBloom(),
+		pathKeys: p.newBloom(),
+		params:   p.newBloom(),
+		patterns: p.newBloom(),
+	}
+}
+
+func (d *bloomDeduper) Keep(c *Candidate) bool {
+	return d.explain(c, &Decision{})
+}
+
+func (d *bloomDeduper) explain(c *Candidate, dec *Decision) bool {
+	p := d.p
+	keys := p.paramKeys(c.Params)
+
+	// Find new params
+	newParams := 0
+	scope := p.paramScopeKey(c.Host, c.Path)
+	for _, key := range keys {
+		if d.params.add(scope + key) {
+			newParams++
+		}
+	}
+
+	path := c.Host + "\x00" + c.Path + "\x00"
+	if !d.paths.has(path) {
+		dec.Reason = ReasonNewPath
+		pattern, ok := p.createPattern(c.Path)
+		if ok && !d.patterns.add(p.hostGroup(c.Host)+"\x00"+pattern) {
+			// Scoped novelty keeps pattern duplicates that bring new params
+			if p.paramScope == ScopeGlobal || newParams == 0 {
+				dec.Reason, dec.Match = ReasonPattern, pattern
+				return false
+			}
+			dec.Reason, dec.Match = ReasonNewParams, pattern
+		}
+		d.remember(path, keys)
+		return true
+	}
+
+	// Path exists, check params
+	novel := newParams > 0
+	for _, key := range keys {
+		if !d.pathKeys.has(path + key) {
+			novel = true
+		}
+	}
+	if !novel {
+		dec.Reason = ReasonParams
+		return false
+	}
+	dec.Reason = ReasonNewParams
+	d.remember(path, keys)
+	return true
+}
+
+// remember records a kept path and its parameter keys.
+func (d *bloomDeduper) remember(path string, keys []string) {
+	d.paths.add(path)
+	for _, key := range keys {
+		d.pathKeys.add(path + key)
+	}
+}
+
+func (d *bloomDeduper) Reset() {
+	d.paths.reset()
+	d.pathKeys.reset()
+	d.params.reset()
+	d.patterns.reset()
+}
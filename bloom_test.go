@@ -0,0 +1,29 @@
+package uro
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Exactly one of concurrent adds of a key reports it as new.
+func TestBloomConcurrentAdd(t *testing.T) {
+	b := newBloomFilter(1000, 0.001)
+	for n := range 200 {
+		key := fmt.Sprintf("https://a.com/%d", n)
+		var wins atomic.Int32
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				if b.add(key) {
+					wins.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		if got := wins.Load(); got != 1 {
+			t.Fatalf("add(%q) won %d times, want 1", key, got)
+		}
+	}
+}
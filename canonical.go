@@ -0,0 +1,247 @@
+package uro
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Canonicalization steps (see Canonicalize and Options.Canonicalize).
+const (
+	CanonScheme     = "scheme"     // lowercase the scheme
+	CanonHost       = "host"       // lowercase the host
+	CanonPort       = "port"       // drop default ports (:80 for http, :443 for https)
+	CanonDots       = "dots"       // resolve "." and ".." path segments
+	CanonPercent    = "percent"    // uppercase percent-encoding hex digits (%2f -> %2F)
+	CanonUnreserved = "unreserved" // decode percent-encoded unreserved characters (%7E -> ~)
+	CanonNFC        = "nfc"        // apply Unicode NFC normalization
+)
+
+var canonSteps = []string{
+	CanonScheme, CanonHost, CanonPort, CanonDots, CanonPercent, CanonUnreserved, CanonNFC,
+}
+
+var defaultPorts = map[string]string{
+	"http": "80", "https": "443", "ws": "80", "wss": "443", "ftp": "21",
+}
+
+// Canonicalize returns the RFC 3986 normal form of a URL, so that e.g.
+// HTTPS://Example.COM:443/a/./b/../c, https://example.com/a/c and
+// https://example.com/a/%63 all become https://example.com/a/c. A port is
+// only dropped if it is the default of the URL's own scheme, so
+// http://example.com:443/ keeps it.
+// Steps select which normalizations are applied (CanonScheme, CanonHost, ...);
+// if none are given, all steps are applied. "all" enables all steps too.
+func Canonicalize(rawURL string, steps ...string) (string, error) {
+	enabled := canonStepSet(steps)
+	if len(enabled) == 0 {
+		enabled = canonStepSet([]string{"all"})
+	}
+	return canonicalize(rawURL, enabled)
+}
+
+// canonStepSet converts step names into a set. Unknown names are ignored.
+func canonStepSet(steps []string) map[string]bool {
+	enabled := make(map[string]bool)
+	for _, step := range cleanArgs(steps) {
+		if step == "all" {
+			for _, s := range canonSteps {
+				enabled[s] = true
+			}
+			continue
+		}
+		for _, s := range canonSteps {
+			if step == s {
+				enabled[s] = true
+			}
+		}
+	}
+	return enabled
+}
+
+func canonicalize(rawURL string, steps map[string]bool) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := u.Scheme
+	if steps[CanonScheme] {
+		scheme = strings.ToLower(scheme)
+	}
+	if u.Opaque != "" {
+		return scheme + ":" + u.Opaque, nil
+	}
+
+	host := u.Host
+	if steps[CanonHost] {
+		host = strings.ToLower(host)
+	}
+	if steps[CanonPort] {
+		if h, port, err := net.SplitHostPort(host); err == nil && port == defaultPorts[strings.ToLower(scheme)] {
+			host = h
+			if strings.Contains(h, ":") {
+				host = "[" + h + "]"
+			}
+		}
+	}
+
+	// Percent-encoding is normalized before dot segments are removed
+	// (RFC 3986 section 6.2.2), so that %2E%2E is resolved as well
+	path, query, fragment := u.EscapedPath(), u.RawQuery, u.EscapedFragment()
+	for _, part := range []*string{&host, &path, &query, &fragment} {
+		if steps[CanonUnreserved] || steps[CanonPercent] {
+			*part = normalizePercent(*part, steps[CanonUnreserved])
+		}
+	}
+	if steps[CanonNFC] {
+		host = norm.NFC.String(host)
+		for _, part := range []*string{&path, &query, &fragment} {
+			*part = normalizeEscapedNFC(*part)
+		}
+	}
+	if steps[CanonDots] {
+		path = removeDotSegments(path)
+	}
+
+	var b strings.Builder
+	if scheme != "" {
+		b.WriteString(scheme + ":")
+	}
+	if host != "" || u.User != nil || scheme != "" {
+		b.WriteString("//")
+	}
+	if u.User != nil {
+		b.WriteString(u.User.String() + "@")
+	}
+	b.WriteString(host)
+	b.WriteString(path)
+	if query != "" || u.ForceQuery {
+		b.WriteString("?" + query)
+	}
+	if fragment != "" {
+		b.WriteString("#" + fragment)
+	}
+	return b.String(), nil
+}
+
+// removeDotSegments implements RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	var out []string
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"):
+			path = path[2:]
+		case strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "/..":
+			path = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "." || path == "..":
+			path = ""
+		default:
+			start := 0
+			if path[0] == '/' {
+				start = 1
+			}
+			end := strings.IndexByte(path[start:], '/')
+			if end < 0 {
+				end = len(path)
+			} else {
+				end += start
+			}
+			out = append(out, path[:end])
+			path = path[end:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// normalizeEscapedNFC applies Unicode NFC to a percent-encoded URL part:
+// non-ASCII bytes are decoded, normalized and encoded again, so that
+// cafe%CC%81 and caf%C3%A9 both become caf%C3%A9. The part is returned
+// unchanged if it is already in NFC.
+func normalizeEscapedNFC(s string) string {
+	var decoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			if c := unhex(s[i+1])<<4 | unhex(s[i+2]); c >= 0x80 {
+				decoded.WriteByte(c)
+				i += 2
+				continue
+			}
+		}
+		decoded.WriteByte(s[i])
+	}
+	if norm.NFC.IsNormalString(decoded.String()) {
+		return s
+	}
+
+	normalized := norm.NFC.String(decoded.String())
+	var b strings.Builder
+	for i := 0; i < len(normalized); i++ {
+		if c := normalized[i]; c >= 0x80 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// normalizePercent uppercases percent-encoding hex digits and, if decode is
+// set, decodes percent-encoded unreserved characters (ALPHA, DIGIT, -._~).
+func normalizePercent(s string, decode bool) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if decode && isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,43 @@
+package uro
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		in    string
+		steps []string
+		want  string
+	}{
+		{"HTTPS://Example.COM:443/a/./b/../c", nil, "https://example.com/a/c"},
+		{"https://example.com/a/c", nil, "https://example.com/a/c"},
+		{"https://example.com/a/%63", nil, "https://example.com/a/c"},
+		// :443 is not the default port of http
+		{"HTTP://Example.COM:443/a/./b/../c", nil, "http://example.com:443/a/c"},
+		{"http://[::1]:80/a/..", nil, "http://[::1]/"},
+		{"http://example.com/%7euser/%2f?q=%7e#%61", nil, "http://example.com/~user/%2F?q=~#a"},
+		{"mailto:A@B.com", nil, "mailto:A@B.com"},
+		// Percent-encoded dot segments are resolved, and the result is stable
+		{"https://example.com/a/%2e%2e/b", nil, "https://example.com/b"},
+		{"https://example.com/b", nil, "https://example.com/b"},
+		// Precomposed and decomposed é have the same NFC form
+		{"https://a.com/caf\u00e9", nil, "https://a.com/caf%C3%A9"},
+		{"https://a.com/cafe\u0301", nil, "https://a.com/caf%C3%A9"},
+		{"https://a.com/cafe%CC%81?q=cafe%cc%81", []string{CanonNFC}, "https://a.com/caf%C3%A9?q=caf%C3%A9"},
+		{"https://a.com/cafe%cc%81", []string{CanonPercent}, "https://a.com/cafe%CC%81"},
+		{"HTTPS://Example.COM:443/a/./b", []string{CanonScheme}, "https://Example.COM:443/a/./b"},
+		{"HTTPS://Example.COM:443/a/./b", []string{"host,port"}, "https://example.com/a/./b"},
+	}
+	for _, tt := range tests {
+		got, err := Canonicalize(tt.in, tt.steps...)
+		if err != nil {
+			t.Errorf("Canonicalize(%q, %q): %v", tt.in, tt.steps, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Canonicalize(%q, %q) = %q, want %q", tt.in, tt.steps, got, tt.want)
+		}
+		if again, _ := Canonicalize(got, tt.steps...); again != got {
+			t.Errorf("Canonicalize(%q, %q) = %q, not idempotent", got, tt.steps, again)
+		}
+	}
+}
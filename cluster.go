@@ -0,0 +1,130 @@
+package uro
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultClusterThreshold is the similarity used when Options.ClusterThreshold is not set.
+const DefaultClusterThreshold = 0.75
+
+var reLocale = regexp.MustCompile(`^[a-z]{2}([-_][a-zA-Z]{2})?$`)
+
+// pathCluster is the representative of a group of structurally similar paths.
+// Token positions that differed between members are marked as variable.
+type pathCluster struct {
+	tokens   [][]string
+	variable [][]bool
+	raw      string // the URL that started the cluster
+}
+
+// tokenizePath splits a path into segments and each segment into tokens
+// separated by "-", "_", "." or "~".
+func tokenizePath(path string) [][]string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	tokens := make([][]string, 0, len(segments))
+	for _, segment := range segments {
+		tokens = append(tokens, strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.' || r == '~'
+		}))
+	}
+	return tokens
+}
+
+// clusterKey groups clusters by host and path depth.
+func clusterKey(host string, tokens [][]string) string {
+	return host + "\x00" + strconv.Itoa(len(tokens))
+}
+
+// matchCluster returns the known cluster of the host the path tokens belong to, or nil.
+// On a match, token positions that differ become variable in the cluster.
+// The caller must hold the shard lock of the host.
+func (p *Processor) matchCluster(host string, tokens [][]string) *pathCluster {
+	for _, c := range p.shardFor(host).clusters[clusterKey(host, tokens)] {
+		if p.similarity(c, tokens) >= p.clusterThreshold {
+			c.merge(tokens)
+			return c
+		}
+	}
+	return nil
+}
+
+// addCluster starts a new cluster with the path of raw as its representative.
+func (p *Processor) addCluster(host string, tokens [][]string, raw string) {
+	c := &pathCluster{
+		tokens:   tokens,
+		variable: make([][]bool, len(tokens)),
+		raw:      raw,
+	}
+	for i, segment := range tokens {
+		c.variable[i] = make([]bool, len(segment))
+	}
+	s, key := p.shardFor(host), clusterKey(host, tokens)
+	s.clusters[key] = append(s.clusters[key], c)
+}
+
+// similarity returns the share of matching tokens between a cluster and a path
+// of the same depth. Tokens match if they are equal, if the cluster position is
+// variable, or if both tokens look like variable values (numbers, IDs, locales).
+func (p *Processor) similarity(c *pathCluster, tokens [][]string) float64 {
+	matched, total := 0, 0
+	for i, segment := range tokens {
+		rep := c.tokens[i]
+		total += max(len(rep), len(segment))
+		if i == 0 && len(rep) == 1 && len(segment) == 1 &&
+			reLocale.MatchString(rep[0]) && reLocale.MatchString(segment[0]) {
+			matched++
+			continue
+		}
+		for j := 0; j < len(rep) && j < len(segment); j++ {
+			if rep[j] == segment[j] || c.variable[i][j] ||
+				(p.isVariableToken(rep[j]) && p.isVariableToken(segment[j])) {
+				matched++
+			}
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(matched) / float64(total)
+}
+
+// merge marks token positions that differ from the path as variable.
+func (c *pathCluster) merge(tokens [][]string) {
+	for i, segment := range tokens {
+		if len(segment) != len(c.tokens[i]) {
+			continue
+		}
+		for j, token := range segment {
+			if token != c.tokens[i][j] {
+				c.variable[i][j] = true
+			}
+		}
+	}
+}
+
+// isVariableToken reports whether a token looks like a value rather than a word:
+// it contains a digit, matches a segment detector or has high entropy.
+func (p *Processor) isVariableToken(token string) bool {
+	if strings.ContainsAny(token, "0123456789") || p.normalizeSegment(token) != "" {
+		return true
+	}
+	return len(token) >= 12 && entropy(token) >= 3.5
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var e float64
+	n := float64(len(s))
+	for _, c := range counts {
+		f := float64(c) / n
+		e -= f * math.Log2(f)
+	}
+	return e
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
 	"sync"
 
@@ -28,30 +34,107 @@ func main() {
 	var (
 		inputFile  string
 		outputFile string
+		stateFile  string
 		whitelist  arrayFlags
 		blacklist  arrayFlags
 		filters    arrayFlags
+		normalize  arrayFlags
+		canonical  arrayFlags
+		hostRules  arrayFlags
+		aliases    arrayFlags
+		preferHost string
+		matrix     bool
+		sessions   bool
+		sessTokens arrayFlags
+		tracking   bool
+		ignored    arrayFlags
+		stripIgn   bool
+		keepParams arrayFlags
 		workers    int
+		ordered    bool
+		maxLine    int
+		valueAware bool
+		paramScope string
+		patScope   string
+		dedup      string
+		cluster    bool
+		clusterMin float64
+		order      string
+		outputMode string
+		original   bool
+		templates  bool
+		hashRoutes bool
+		brackets   bool
+		repeated   bool
 		stream     bool
+		lowMemory  bool
+		expected   int
+		fpRate     float64
+		explain    bool
+		stats      bool
+		statsFmt   string
+		diffFmt    string
 		showHelp   bool
 		showVer    bool
 	)
 
 	flag.StringVar(&inputFile, "i", "", "file containing urls")
 	flag.StringVar(&outputFile, "o", "", "output file")
+	flag.StringVar(&stateFile, "state", "", "state file: load before processing, save after (output only new URLs)")
 	flag.Var(&whitelist, "w", "only keep these extensions (can be specified multiple times)")
 	flag.Var(&whitelist, "whitelist", "only keep these extensions")
 	flag.Var(&blacklist, "b", "remove these extensions (can be specified multiple times)")
 	flag.Var(&blacklist, "blacklist", "remove these extensions")
 	flag.Var(&filters, "f", "additional filters (can be specified multiple times)")
 	flag.Var(&filters, "filters", "additional filters")
+	flag.Var(&normalize, "normalize", "path segment detectors: uuid,hex,ulid,base64,date,email,all")
+	flag.BoolVar(&valueAware, "value-aware", false, "deduplicate parameters by name and value type")
+	flag.StringVar(&paramScope, "param-scope", uro.ScopeGlobal, "where parameter novelty is tracked: global, host, path")
+	flag.StringVar(&patScope, "pattern-scope", uro.ScopeGlobal, "where path patterns are deduplicated: global, host, domain")
+	flag.StringVar(&dedup, "dedup", uro.DedupClassic, "deduplication strategy: classic, exact, keys, template")
+	flag.BoolVar(&cluster, "cluster", false, "drop paths structurally similar to already kept ones")
+	flag.Float64Var(&clusterMin, "cluster-threshold", uro.DefaultClusterThreshold, "minimum similarity (0..1) for path clustering")
+	flag.StringVar(&order, "order", uro.OrderInput, "output order: input, lex, host")
+	flag.StringVar(&outputMode, "output", "", "form of kept URLs: canonical, original, template (default: canonical, original with --stream)")
+	flag.BoolVar(&original, "original", false, "output kept URLs exactly as they appear in the input (-output original)")
+	flag.BoolVar(&templates, "templates", false, "output endpoint templates with the number of URLs collapsed into each (-output template)")
+	flag.BoolVar(&hashRoutes, "hash-routes", false, "treat #/ and #!/ fragments as paths (single-page apps)")
+	flag.BoolVar(&brackets, "group-brackets", false, "group bracket parameters (user[name], user[role] -> user[*])")
+	flag.BoolVar(&repeated, "keep-repeated", false, "keep URLs with repeated parameters (a=1&a=2) as new")
+	flag.Var(&canonical, "canonicalize", "canonicalization steps: scheme,host,port,dots,percent,unreserved,nfc,all")
+	flag.Var(&hostRules, "host-rules", "host equivalence rules: scheme,www,ports,idn")
+	flag.Var(&aliases, "alias", "treat host as equivalent to another (cdn.example.com=example.com)")
+	flag.StringVar(&preferHost, "prefer-host", uro.PreferFirst, "representative of equivalent hosts: first, https, http, www, apex")
+	flag.BoolVar(&matrix, "matrix", false, "parse path matrix parameters (/item;id=5) as parameters")
+	flag.BoolVar(&sessions, "strip-sessions", false, "remove session tokens (jsessionid, PHPSESSID, ASP.NET cookieless IDs)")
+	flag.Var(&sessTokens, "session-tokens", "session parameter names to remove (replaces the default list)")
+	flag.BoolVar(&tracking, "ignore-tracking", false, "ignore tracking and cache-buster parameters (utm_*, fbclid, _, v, ...)")
+	flag.Var(&ignored, "ignore-params", "parameters ignored for novelty (globs allowed, e.g. utm_*)")
+	flag.BoolVar(&stripIgn, "strip-ignored", false, "remove ignored parameters from output")
+	flag.Var(&keepParams, "keep-params", "keep only these parameters (globs allowed)")
 	flag.IntVar(&workers, "j", 0, "number of parallel workers (0=sequential, -1=NumCPU)")
+	flag.BoolVar(&ordered, "ordered", false, "deterministic parallel mode, output identical to sequential")
+	flag.IntVar(&maxLine, "max-line-length", uro.DefaultMaxLineLength, "longest accepted input line in bytes")
 	flag.BoolVar(&stream, "stream", false, "streaming mode (output URLs as they are processed)")
+	flag.BoolVar(&lowMemory, "low-memory", false, "bounded memory with Bloom filters (implies --stream, may drop a few new URLs)")
+	flag.IntVar(&expected, "expected", uro.DefaultExpectedURLs, "number of distinct URLs the low-memory filters are sized for")
+	flag.Float64Var(&fpRate, "fp-rate", uro.DefaultFalsePositiveRate, "target false drop rate in low-memory mode")
+	flag.BoolVar(&explain, "explain", false, "print every input URL with the reason it was kept or dropped")
+	flag.BoolVar(&stats, "stats", false, "print statistics to stderr after processing")
+	flag.StringVar(&statsFmt, "stats-format", "text", "statistics format: text, json")
+	flag.StringVar(&diffFmt, "format", "text", "diff output format: text, json")
 	flag.BoolVar(&showHelp, "h", false, "show help")
 	flag.BoolVar(&showHelp, "help", false, "show help")
 	flag.BoolVar(&showVer, "version", false, "show version")
 
-	flag.Parse()
+	// Подкоманды: uro merge [опции] state1 state2 ..., uro diff [опции] old new
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && (args[0] == "merge" || args[0] == "diff") {
+		command, args = args[0], args[1:]
+	}
+	merge, diff := command == "merge", command == "diff"
+	flag.CommandLine.Parse(args)
 
 	if showVer {
 		fmt.Println("uro version", version)
@@ -87,28 +170,92 @@ func main() {
 		output = os.Stdout
 	}
 
+	// Форма вывода одинакова в обычном и streaming режиме, если задана явно
+	form, err := outputForm(outputMode, original, templates)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+
 	// Создаём опции для процессора
 	opts := &uro.Options{
-		Whitelist: cleanArgs(whitelist),
-		Blacklist: cleanArgs(blacklist),
-		Filters:   cleanFilters,
-		KeepSlash: keepSlash,
-		Workers:   workers,
+		Whitelist:        cleanArgs(whitelist),
+		Blacklist:        cleanArgs(blacklist),
+		Filters:          cleanFilters,
+		KeepSlash:        keepSlash,
+		Workers:          workers,
+		Ordered:          ordered,
+		MaxLineLength:    maxLine,
+		Normalizers:      cleanArgs(normalize),
+		ValueAware:       valueAware,
+		ParamScope:       paramScope,
+		PatternScope:     patScope,
+		Dedup:            dedup,
+		Cluster:          cluster,
+		ClusterThreshold: clusterMin,
+		Order:            order,
+		Output:           form,
+		HashRoutes:       hashRoutes,
+		GroupBrackets:    brackets,
+		KeepRepeated:     repeated,
+		Canonicalize:     cleanArgs(canonical),
+		HostRules:        buildHostRules(cleanArgs(hostRules), aliases, preferHost),
+		MatrixParams:     matrix,
+		StripSessions:    sessions || len(sessTokens) > 0,
+		SessionTokens:    cleanArgs(sessTokens),
+		IgnoreTracking:   tracking,
+		IgnoreParams:     cleanArgs(ignored),
+		StripIgnored:     stripIgn,
+		KeepParams:       cleanArgs(keepParams),
+	}
+
+	// Режим низкого потребления памяти работает только в streaming режиме
+	if lowMemory {
+		stream = true
+		opts.LowMemory = true
+		opts.ExpectedURLs = expected
+		opts.FalsePositiveRate = fpRate
+	}
+
+	// Счётчики шаблонов известны только после обработки всего ввода
+	if form == uro.OutputTemplate && stream {
+		fmt.Fprintln(os.Stderr, "[ERROR] template output can't be used with --stream or --low-memory")
+		os.Exit(1)
 	}
 
-	// Настраиваем streaming режим
-	var streamMu sync.Mutex
-	if stream {
+	// Формат статистики проверяем до обработки ввода
+	if f := strings.ToLower(statsFmt); f != "text" && f != "json" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Unknown -stats-format %q (text, json)\n", statsFmt)
+		os.Exit(1)
+	}
+
+	// Настраиваем streaming режим; после первой ошибки записи вывод
+	// прекращается, а ошибка сообщается после обработки
+	var (
+		streamMu  sync.Mutex
+		streamErr error
+	)
+	if stream && !explain {
 		opts.StreamOutput = func(url string) {
 			streamMu.Lock()
-			fmt.Fprintln(output, url)
-			streamMu.Unlock()
+			defer streamMu.Unlock()
+			if streamErr == nil {
+				_, streamErr = fmt.Fprintln(output, url)
+			}
 		}
 	}
 
 	// Создаём процессор
 	proc := uro.NewProcessor(opts)
 
+	// Загружаем состояние предыдущего запуска
+	if stateFile != "" {
+		if err := loadState(proc, stateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot load state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Определяем источник ввода
 	var input *os.File
 	if inputFile != "" {
@@ -119,7 +266,7 @@ func main() {
 		}
 		defer f.Close()
 		input = f
-	} else {
+	} else if !merge && !diff {
 		// Проверяем, есть ли данные в stdin
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeCharDevice) != 0 {
@@ -129,21 +276,323 @@ func main() {
 		input = os.Stdin
 	}
 
-	// Обрабатываем URL
-	proc.ProcessReader(input)
+	// Прерывание по Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := false
+	if diff {
+		// Сравниваем два списка URL; состояние и статистика не используются
+		if stateFile != "" {
+			fmt.Fprintln(os.Stderr, "[ERROR] -state can't be used with diff")
+			os.Exit(1)
+		}
+		if err := diffFiles(ctx, output, opts, flag.Args(), diffFmt); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot diff: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	} else if merge {
+		// Объединяем состояния шардов
+		if err := mergeStates(proc, opts, flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot merge states: %v\n", err)
+			os.Exit(1)
+		}
+		if !stream {
+			if err := proc.WriteResults(output); err != nil {
+				fmt.Fprintf(os.Stderr, "[ERROR] Cannot write results: %v\n", err)
+				failed = true
+			}
+		}
+	} else if explain {
+		// Режим объяснения: решение для каждого URL
+		if err := explainInput(ctx, proc, input, output, maxLine); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Explain failed: %v\n", err)
+			failed = true
+		}
+	} else {
+		// Обрабатываем URL; при ошибке ввода выводим то, что успели обработать
+		if _, err := proc.ProcessReaderContext(ctx, input); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot read input: %v\n", err)
+			failed = true
+		}
+
+		// Выводим результаты (если не streaming режим)
+		if !stream {
+			if err := proc.WriteResults(output); err != nil {
+				fmt.Fprintf(os.Stderr, "[ERROR] Cannot write results: %v\n", err)
+				failed = true
+			}
+		}
+	}
+
+	if streamErr != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Cannot write results: %v\n", streamErr)
+		failed = true
+	}
+
+	// Статистика
+	if stats {
+		if err := printStats(os.Stderr, proc.Stats(), statsFmt); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot print stats: %v\n", err)
+			failed = true
+		}
+	}
+
+	// Сохраняем состояние, только если результаты выведены без ошибок
+	if stateFile != "" && !failed {
+		if err := saveState(proc, stateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Cannot save state: %v\n", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// mergeStates загружает состояния из файлов и объединяет их в proc по порядку
+func mergeStates(proc *uro.Processor, opts *uro.Options, paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no state files given")
+	}
+	for _, path := range paths {
+		shard := uro.NewProcessor(opts)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = shard.Load(bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := proc.Merge(shard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffFiles сравнивает эндпоинты двух файлов с URL и выводит разницу
+func diffFiles(ctx context.Context, w io.Writer, opts *uro.Options, paths []string, format string) error {
+	if len(paths) != 2 {
+		return fmt.Errorf("expected old and new URL files, got %d files", len(paths))
+	}
+	oldFile, err := os.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer oldFile.Close()
+	newFile, err := os.Open(paths[1])
+	if err != nil {
+		return err
+	}
+	defer newFile.Close()
+
+	d, err := uro.DiffReaders(ctx, oldFile, newFile, opts)
+	if err != nil {
+		return err
+	}
+	return printDiff(w, d, format)
+}
+
+// printDiff выводит разницу: "+ эндпоинт" / "- эндпоинт" и "+ param имя" / "- param имя"
+func printDiff(w io.Writer, d *uro.DiffResult, format string) error {
+	if strings.EqualFold(format, "json") {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, e := range d.Added {
+		fmt.Fprintln(bw, "+", e)
+	}
+	for _, e := range d.Removed {
+		fmt.Fprintln(bw, "-", e)
+	}
+	for _, param := range d.AddedParams {
+		fmt.Fprintln(bw, "+ param", param)
+	}
+	for _, param := range d.RemovedParams {
+		fmt.Fprintln(bw, "- param", param)
+	}
+	return bw.Flush()
+}
+
+// loadState загружает состояние из файла; отсутствующий файл — первый запуск
+func loadState(proc *uro.Processor, path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return proc.Load(bufio.NewReader(f))
+}
+
+// saveState атомарно записывает состояние в файл
+func saveState(proc *uro.Processor, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = proc.Save(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// printStats печатает статистику в текстовом виде или в JSON
+func printStats(w io.Writer, s uro.Stats, format string) error {
+	if strings.EqualFold(format, "json") {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	fmt.Fprintf(w, "input:           %d\n", s.Input)
+	fmt.Fprintf(w, "kept:            %d\n", s.Kept)
+	fmt.Fprintf(w, "filtered:        %d%s\n", sum(s.Filtered), breakdown(s.Filtered))
+	fmt.Fprintf(w, "duplicates:      %d%s\n", sum(s.Duplicates), breakdown(s.Duplicates))
+	fmt.Fprintf(w, "invalid:         %d\n", s.Invalid)
+	fmt.Fprintf(w, "empty:           %d\n", s.Empty)
+	fmt.Fprintf(w, "unique params:   %d\n", s.UniqueParams)
+	fmt.Fprintf(w, "unique patterns: %d\n", s.UniquePatterns)
+
+	hosts := make([]string, 0, len(s.Hosts))
+	for host := range s.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	if len(hosts) > 0 {
+		fmt.Fprintln(w, "hosts:")
+	}
+	for _, host := range hosts {
+		h := s.Hosts[host]
+		fmt.Fprintf(w, "  %s input=%d kept=%d filtered=%d duplicates=%d\n",
+			host, h.Input, h.Kept, h.Filtered, h.Duplicates)
+	}
+	return nil
+}
 
-	// Выводим результаты (если не streaming режим)
-	if !stream {
-		proc.WriteResults(output)
+// sum возвращает сумму счётчиков
+func sum(counts map[string]int64) int64 {
+	var total int64
+	for _, n := range counts {
+		total += n
 	}
+	return total
 }
 
-// cleanArgs очищает и нормализует аргументы
+// breakdown форматирует счётчики как " (blacklist 10, removecontent 2)"
+func breakdown(counts map[string]int64) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d", name, counts[name]))
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// explainInput обрабатывает URL по одному и печатает каждый с причиной решения
+func explainInput(ctx context.Context, proc *uro.Processor, input io.Reader, output io.Writer, maxLine int) error {
+	scanner := bufio.NewScanner(input)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, maxLine)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(output, "%s\t%s\n", line, proc.ProcessExplain(line)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// buildHostRules собирает правила эквивалентности хостов из аргументов CLI
+func buildHostRules(rules []string, aliases []string, prefer string) uro.HostRules {
+	hr := uro.HostRules{
+		Aliases: uro.ParseHostAliases(aliases),
+		Prefer:  prefer,
+	}
+	for _, r := range rules {
+		switch r {
+		case "scheme":
+			hr.IgnoreScheme = true
+		case "www":
+			hr.FoldWWW = true
+		case "ports":
+			hr.FoldPorts = true
+		case "idn":
+			hr.IDN = true
+		}
+	}
+	return hr
+}
+
+// outputForm возвращает форму вывода URL из -output или флагов --original и --templates
+func outputForm(output string, original, templates bool) (string, error) {
+	short, shortFlag := "", ""
+	switch {
+	case templates:
+		short, shortFlag = uro.OutputTemplate, "--templates"
+	case original:
+		short, shortFlag = uro.OutputOriginal, "--original"
+	}
+
+	switch output = strings.ToLower(strings.TrimSpace(output)); output {
+	case "":
+		return short, nil // "" — canonical, в streaming режиме — исходный текст
+	case uro.OutputCanonical, uro.OutputOriginal, uro.OutputTemplate:
+		if short != "" && short != output {
+			return "", fmt.Errorf("-output %s conflicts with %s", output, shortFlag)
+		}
+		return output, nil
+	}
+	return "", fmt.Errorf("unknown -output %q (canonical, original, template)", output)
+}
+
+// cleanArgs очищает и нормализует аргументы, сохраняя порядок первого появления
 func cleanArgs(args []string) []string {
 	if len(args) == 0 {
 		return nil
 	}
-	result := make(map[string]struct{})
+	seen := make(map[string]struct{})
+	var output []string
+	add := func(arg string) {
+		if _, ok := seen[arg]; !ok {
+			seen[arg] = struct{}{}
+			output = append(output, arg)
+		}
+	}
 	for _, arg := range args {
 		arg = strings.TrimSpace(arg)
 		if arg == "" {
@@ -153,17 +602,13 @@ func cleanArgs(args []string) []string {
 			for _, part := range strings.Split(arg, ",") {
 				part = strings.TrimSpace(strings.ToLower(part))
 				if part != "" {
-					result[part] = struct{}{}
+					add(part)
 				}
 			}
 		} else {
-			result[strings.ToLower(arg)] = struct{}{}
+			add(strings.ToLower(arg))
 		}
 	}
-	output := make([]string, 0, len(result))
-	for k := range result {
-		output = append(output, k)
-	}
 	return output
 }
 
@@ -174,15 +619,65 @@ Usage:
   uro [options]
   cat urls.txt | uro
   uro -i input.txt -o output.txt
+  uro merge [options] state1 state2 ...
+  uro diff [options] old.txt new.txt
 
 Options:
   -i <file>        Input file containing URLs (default: stdin)
   -o <file>        Output file (default: stdout)
+  -state <file>    Load state before processing and save it after; only new URLs are output
   -w, -whitelist   Only keep these extensions
   -b, -blacklist   Remove these extensions
   -f, -filters     Additional filters (see below)
+  -normalize <d>   Collapse variable path segments (see below)
+  --value-aware    Deduplicate parameters by name and value type (int, url, path, ...)
+  -param-scope <s> Track parameter novelty globally, per host or per path (default: global)
+  -pattern-scope <s>
+                   Deduplicate path patterns globally, per host or per domain (default: global)
+  -dedup <name>    Deduplication strategy (see below)
+  --cluster        Drop paths structurally similar to already kept ones
+  -cluster-threshold <f>
+                   Minimum similarity for clustering, 0..1 (default: 0.75)
+  -order <mode>    Output order: input (default), lex, host
+  -output <form>   Form of kept URLs, in both normal and streaming mode:
+                   canonical (default), original, template
+                   (without -output, streamed URLs are output as in the input)
+  --original       Same as -output original: kept URLs exactly as in the input
+  --templates      Same as -output template: endpoint templates (/users/{int}?sort=&page=),
+                   each followed by a tab and the number of input URLs collapsed into it
+  --hash-routes    Treat #/ and #!/ fragments as paths (single-page apps)
+  --group-brackets Group bracket parameters (user[name], user[role] -> user[*])
+  --keep-repeated  Keep URLs with repeated parameters (a=1&a=2) as new
+  -canonicalize <s>
+                   Canonicalize URLs before deduplication (see below)
+  -host-rules <r>  Treat hosts as equivalent: scheme, www, ports, idn (comma-separated)
+  -alias <a=h>     Treat host a as host h (can be specified multiple times)
+  -prefer-host <p> Host shown for equivalent hosts: first, https, http, www, apex
+  --matrix         Parse path matrix parameters (/item;id=5;color=red) as parameters
+  --strip-sessions Remove session tokens (jsessionid, PHPSESSID, ASP.NET cookieless IDs)
+  -session-tokens <names>
+                   Session parameter names to remove, globs allowed (replaces the default list)
+  --ignore-tracking
+                   Ignore tracking and cache-buster parameters (utm_*, fbclid, gclid, _, v, cb, ts)
+  -ignore-params <names>
+                   Parameters ignored for novelty, globs allowed (e.g. utm_*)
+  --strip-ignored  Remove ignored parameters from output
+  -keep-params <names>
+                   Keep only these parameters, globs allowed
   -j <num>         Number of parallel workers (0=sequential, -1=NumCPU)
+  --ordered        With -j: commit decisions in input order, output identical to sequential
+  -max-line-length <n>
+                   Longest accepted input line in bytes (default: 1048576)
   --stream         Output URLs immediately as they are processed
+  --low-memory     Bounded memory with Bloom filters (implies --stream);
+                   a small share of new URLs may be dropped, see -fp-rate
+  -expected <n>    Distinct URLs the low-memory filters are sized for (default: 10000000)
+  -fp-rate <f>     Target false drop rate in low-memory mode (default: 0.001)
+  --explain        Print every input URL with the reason it was kept or dropped
+  --stats          Print statistics to stderr after processing
+  -stats-format <fmt>
+                   Format of --stats: text (default), json
+  -format <fmt>    Output format of diff: text (default), json
   -h, -help        Show this help
   --version        Show version
 
@@ -195,6 +690,32 @@ Filters:
   keepcontent   Keep human-written content (blogs, posts)
   keepslash     Keep trailing slash in URLs
   vuln          Only URLs with potentially vulnerable parameters
+  hpp           Only URLs with repeated parameters (HTTP parameter pollution)
+
+Path segment detectors (-normalize):
+  uuid          UUIDs
+  hex           Hex hashes and identifiers (16+ chars)
+  ulid          ULIDs
+  base64        Base62/base64 tokens mixing letter case and digits
+  date          Dates like 2024-05-01
+  email         Email addresses
+  all           All of the above
+
+Canonicalization steps (-canonicalize):
+  scheme        Lowercase the scheme
+  host          Lowercase the host
+  port          Drop default ports (:80, :443)
+  dots          Resolve . and .. path segments
+  percent       Uppercase percent-encoding (%2f -> %2F)
+  unreserved    Decode unreserved characters (%63 -> c)
+  nfc           Unicode NFC normalization
+  all           All of the above
+
+Deduplication strategies (-dedup):
+  classic       New paths, numeric patterns and new parameters (default)
+  exact         One URL per exact host, path and parameters
+  keys          One URL per host, path and parameter names
+  template      One URL per host and path template (/users/{int})
 
 Examples:
   cat urls.txt | uro
@@ -202,6 +723,16 @@ Examples:
   uro -w php,html,asp < urls.txt
   uro -w php -w html -w asp < urls.txt
   uro -f hasparams -f vuln < urls.txt
+  uro -normalize uuid,hex < urls.txt   # collapse /users/<uuid> paths
   uro -j 4 < urls.txt                  # 4 parallel workers
-  uro -j -1 --stream < urls.txt        # NumCPU workers, streaming output`)
+  uro -j -1 --stream < urls.txt        # NumCPU workers, streaming output
+  uro --stream -output canonical < urls.txt
+  uro -j -1 --ordered < urls.txt       # parallel, same output as sequential
+  uro --explain < urls.txt             # why each URL was kept or dropped
+  uro --stats -i urls.txt              # statistics to stderr
+  uro --stats -stats-format json < urls.txt
+  uro -state recon.state < today.txt   # only URLs new since the previous run
+  uro merge shard1.state shard2.state  # combine processors of split inputs
+  uro diff last-week.txt today.txt     # endpoints and parameters added or removed
+  uro --templates < urls.txt           # endpoint inventory with URL counts`)
 }
@@ -0,0 +1,229 @@
+package uro
+
+import (
+	"sort"
+	"strings"
+)
+
+// Built-in deduplication strategies (see Options.Dedup).
+const (
+	DedupClassic  = "classic"
+	DedupExact    = "exact"
+	DedupKeys     = "keys"
+	DedupTemplate = "template"
+)
+
+// Candidate is a URL that passed all filters and is checked for duplicates.
+type Candidate struct {
+	// Host is the scheme and host part of the URL (https://example.com).
+	// With Options.HostRules it is the bucket key shared by equivalent hosts
+	// (e.g. *://example.com when the scheme is ignored).
+	Host string
+	// Path is the URL path.
+	Path string
+	// Params contains the query parameters in source order.
+	Params Params
+	// Template is the path with variable segments replaced by placeholders
+	// (/users/{int}/orders). It equals Path if no segment is variable.
+	Template string
+	// Raw is the input URL.
+	Raw string
+
+	routeParams int   // trailing parameters from a hash route (HashRoutes)
+	seq         int64 // position assigned in parallel mode, or 0
+}
+
+// Deduper decides whether a filtered URL is new or a duplicate.
+// Calls of a custom Deduper are serialized by the processor, so
+// implementations don't need their own synchronization.
+type Deduper interface {
+	// Keep reports whether the candidate should be kept.
+	// A kept candidate must be remembered so that its duplicates are dropped.
+	Keep(c *Candidate) bool
+
+	// Reset forgets all remembered candidates.
+	Reset()
+}
+
+// setupDeduper selects the deduplication strategy from options.
+func (p *Processor) setupDeduper() {
+	if p.opts.Deduper != nil {
+		p.deduper = p.opts.Deduper
+		return
+	}
+
+	var key func(*Candidate) string
+	switch strings.ToLower(strings.TrimSpace(p.opts.Dedup)) {
+	case DedupExact:
+		key = func(c *Candidate) string {
+			return c.Host + c.Path + "?" + strings.Join(sortedPairs(p.noveltyParams(c.Params)), "&")
+		}
+	case DedupKeys:
+		key = func(c *Candidate) string {
+			keys := p.paramKeys(c.Params)
+			sort.Strings(keys)
+			return c.Host + c.Path + "?" + strings.Join(keys, "&")
+		}
+	case DedupTemplate:
+		key = func(c *Candidate) string {
+			return c.Host + c.Template
+		}
+	}
+
+	switch {
+	case key != nil:
+		d := newKeyDeduper(key)
+		if p.lowMemory {
+			d.bloom = p.newBloom()
+		}
+		p.deduper = d
+	case p.lowMemory:
+		p.deduper = newBloomDeduper(p)
+	default:
+		p.deduper = &classicDeduper{p: p}
+	}
+}
+
+// keyDeduper keeps one candidate per key. It is safe for concurrent use.
+type keyDeduper struct {
+	key   func(*Candidate) string
+	seen  concurrentMap[string] // key -> first URL
+	bloom *bloomFilter          // replaces seen in low-memory mode
+}
+
+func newKeyDeduper(key func(*Candidate) string) *keyDeduper {
+	return &keyDeduper{key: key}
+}
+
+func (d *keyDeduper) Keep(c *Candidate) bool {
+	return d.explain(c, &Decision{})
+}
+
+func (d *keyDeduper) explain(c *Candidate, dec *Decision) bool {
+	key := d.key(c)
+	if d.bloom != nil {
+		if !d.bloom.add(key) {
+			dec.Reason = ReasonDuplicate
+			return false
+		}
+		dec.Reason = ReasonNew
+		return true
+	}
+	if first, ok := d.seen.loadOrStore(key, c.Raw); ok {
+		dec.Reason, dec.DuplicateOf = ReasonDuplicate, first
+		return false
+	}
+	dec.Reason = ReasonNew
+	return true
+}
+
+func (d *keyDeduper) Reset() {
+	d.seen.clear()
+	if d.bloom != nil {
+		d.bloom.reset()
+	}
+}
+
+// classicDeduper implements the original uro logic: new paths are kept unless
+// they match a seen pattern, and known paths are kept only with new parameters.
+// It works on the processor state (urlMap of the host shard, paramsSeen,
+// patternsSeen) and is called with the shard lock of the candidate host held.
+type classicDeduper struct {
+	p *Processor
+}
+
+func (d *classicDeduper) Keep(c *Candidate) bool {
+	return d.explain(c, &Decision{})
+}
+
+func (d *classicDeduper) explain(c *Candidate, dec *Decision) bool {
+	p := d.p
+
+	// Find and remember new params
+	newParams := []string{}
+	scope := p.paramScopeKey(c.Host, c.Path)
+	for _, param := range p.paramKeys(c.Params) {
+		param = scope + param
+		if p.paramsSeen.add(param, struct{}{}) {
+			newParams = append(newParams, param)
+		}
+	}
+
+	// Check if path exists
+	existing, pathExists := p.shardFor(c.Host).urlMap[c.Host][c.Path]
+
+	if !pathExists {
+		// Check numeric and normalized segment patterns
+		if pattern, ok := p.createPattern(c.Path); ok {
+			group := p.hostGroup(c.Host)
+			key := group + "\x00" + pattern
+			seen, loaded := p.patternsSeen.loadOrStore(key, Pattern{Pattern: pattern, Group: group, Host: c.Host, URL: c.Raw})
+			if loaded {
+				// Scoped novelty keeps pattern duplicates that bring new params
+				if p.paramScope == ScopeGlobal || len(newParams) == 0 {
+					dec.Reason, dec.Match, dec.DuplicateOf = ReasonPattern, pattern, seen.URL
+					return false
+				}
+				dec.Reason, dec.Match = ReasonNewParams, pattern
+				return true
+			}
+		}
+		dec.Reason = ReasonNewPath
+		return true
+	}
+
+	// Path exists, check params
+	if len(newParams) > 0 || (len(c.Params) > 0 && p.compareParams(existing.entries, c.Params)) {
+		dec.Reason = ReasonNewParams
+		return true
+	}
+	dec.Reason, dec.DuplicateOf = ReasonParams, p.coveringURL(existing, c.Params)
+	return false
+}
+
+// Reset is a no-op: the classic state is cleared by Processor.Reset.
+func (d *classicDeduper) Reset() {}
+
+// store records a kept URL in the urlMap of its shard and returns it as a result.
+// In low-memory mode nothing is recorded. The caller must hold the shard lock.
+func (p *Processor) store(c *Candidate) result {
+	seq := c.seq
+	if seq == 0 {
+		seq = p.seq.Add(1)
+	}
+	if p.lowMemory {
+		return result{seq: seq, host: p.displayHost(c.Host), path: c.Path, raw: c.Raw, params: c.Params, routeParams: c.routeParams}
+	}
+	urlMap := p.shardFor(c.Host).urlMap
+	if _, ok := urlMap[c.Host]; !ok {
+		urlMap[c.Host] = make(map[string]*pathEntry)
+	}
+	pe, ok := urlMap[c.Host][c.Path]
+	if !ok {
+		pe = &pathEntry{seq: seq, raw: c.Raw}
+		urlMap[c.Host][c.Path] = pe
+	}
+
+	r := result{seq: seq, host: p.displayHost(c.Host), path: c.Path, raw: c.Raw}
+	if len(c.Params) > 0 {
+		pe.entries = append(pe.entries, &urlEntry{seq: seq, raw: c.Raw, params: c.Params, routeParams: c.routeParams})
+		r.params, r.routeParams = c.Params, c.routeParams
+	}
+	return r
+}
+
+// pathTemplate replaces every variable segment of the path with a placeholder
+// ({int} for numbers, {name} for detectors).
+func (p *Processor) pathTemplate(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		switch token := p.normalizeSegment(part); token {
+		case "":
+		case `\d+`:
+			parts[i] = "{int}"
+		default:
+			parts[i] = token
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,200 @@
+package uro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Endpoint is a path of a host with a set of parameters: all kept URLs
+// with the same host, path pattern and parameter keys are one endpoint.
+type Endpoint struct {
+	// Host is the scheme://host of the endpoint.
+	Host string `json:"host"`
+	// Path is the collapsed path pattern (e.g. /users/\d+), or the path
+	// if it has no variable segments.
+	Path string `json:"path"`
+	// Params are the sorted parameter novelty keys: names, or name=class
+	// pairs with ValueAware, and name[repeated] for a repeated parameter.
+	// Ignored parameters are left out.
+	Params []string `json:"params"`
+	// URL is the first kept URL of the endpoint, in the output form.
+	URL string `json:"url"`
+}
+
+// String returns the endpoint as host, path and parameter keys,
+// e.g. https://example.com/users/\d+?page&sort.
+func (e Endpoint) String() string {
+	if len(e.Params) == 0 {
+		return e.Host + e.Path
+	}
+	return e.Host + e.Path + "?" + strings.Join(e.Params, "&")
+}
+
+func (e Endpoint) key() string {
+	return e.Host + "\x00" + e.Path + "\x00" + strings.Join(e.Params, "&")
+}
+
+// DiffResult lists the endpoints and parameters found in only one of two
+// URL lists (see Diff).
+type DiffResult struct {
+	// Added are the endpoints only the new list has.
+	Added []Endpoint `json:"added"`
+	// Removed are the endpoints only the old list has.
+	Removed []Endpoint `json:"removed"`
+	// AddedParams are the parameter keys only the new list has.
+	AddedParams []string `json:"added_params"`
+	// RemovedParams are the parameter keys only the old list has.
+	RemovedParams []string `json:"removed_params"`
+}
+
+// Diff compares the endpoints of the URLs kept by two processors, which
+// should use the same options. URLs of a loaded state count as kept.
+// Diff fails if either processor is in streaming mode.
+func Diff(old, new *Processor) (*DiffResult, error) {
+	if old.streaming || new.streaming {
+		return nil, errors.New("uro: processors in streaming mode keep no URLs to diff")
+	}
+	return diffEndpoints(old.endpoints(), new.endpoints()), nil
+}
+
+// DiffReaders runs two URL lists (one per line) through processors with the
+// same options and compares the endpoints they keep. StreamOutput and
+// LowMemory are ignored. A custom Deduper is reset before the new list.
+func DiffReaders(ctx context.Context, oldURLs, newURLs io.Reader, opts *Options) (*DiffResult, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	o.StreamOutput, o.LowMemory = nil, false
+
+	old := NewProcessor(&o)
+	if _, err := old.ProcessReaderContext(ctx, oldURLs); err != nil {
+		return nil, fmt.Errorf("uro: cannot read old URLs: %w", err)
+	}
+	oldEndpoints := old.endpoints()
+
+	cur := NewProcessor(&o)
+	cur.Reset()
+	if _, err := cur.ProcessReaderContext(ctx, newURLs); err != nil {
+		return nil, fmt.Errorf("uro: cannot read new URLs: %w", err)
+	}
+	return diffEndpoints(oldEndpoints, cur.endpoints()), nil
+}
+
+// endpoints returns the endpoints of the stored URLs by key. As in Results,
+// a path kept without parameters is left out once it has URLs with them.
+func (p *Processor) endpoints() map[string]Endpoint {
+	type first struct {
+		seq int64
+		e   Endpoint
+	}
+	found := make(map[string]first)
+	add := func(r result) {
+		e := Endpoint{Host: r.host, Path: r.path, Params: p.endpointParams(r.params)}
+		if pattern, ok := p.createPattern(r.path); ok {
+			e.Path = pattern
+		}
+		key := e.key()
+		if f, ok := found[key]; ok && f.seq < r.seq {
+			return
+		}
+		e.URL = p.format(r)
+		found[key] = first{r.seq, e}
+	}
+
+	p.lockAll()
+	for _, sh := range p.shards {
+		for key, paths := range sh.urlMap {
+			host := p.displayHost(key)
+			for path, pe := range paths {
+				if len(pe.entries) == 0 {
+					add(result{seq: pe.seq, host: host, path: path, raw: pe.raw})
+				}
+				for _, e := range pe.entries {
+					add(result{
+						seq:         e.seq,
+						host:        host,
+						path:        path,
+						raw:         e.raw,
+						params:      e.params,
+						routeParams: e.routeParams,
+					})
+				}
+			}
+		}
+	}
+	p.unlockAll()
+
+	endpoints := make(map[string]Endpoint, len(found))
+	for key, f := range found {
+		endpoints[key] = f.e
+	}
+	return endpoints
+}
+
+// endpointParams returns the sorted novelty keys of params in a printable
+// form: the extra key of a repeated parameter a becomes a[repeated].
+func (p *Processor) endpointParams(params Params) []string {
+	keys := p.paramKeys(params)
+	for i, key := range keys {
+		if name, ok := strings.CutSuffix(key, repeatedKey); ok {
+			keys[i] = name + "[repeated]"
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// diffEndpoints compares two endpoint sets.
+func diffEndpoints(old, new map[string]Endpoint) *DiffResult {
+	d := &DiffResult{
+		Added:         onlyIn(new, old),
+		Removed:       onlyIn(old, new),
+		AddedParams:   []string{},
+		RemovedParams: []string{},
+	}
+
+	oldParams, newParams := paramSet(old), paramSet(new)
+	for param := range newParams {
+		if _, ok := oldParams[param]; !ok {
+			d.AddedParams = append(d.AddedParams, param)
+		}
+	}
+	for param := range oldParams {
+		if _, ok := newParams[param]; !ok {
+			d.RemovedParams = append(d.RemovedParams, param)
+		}
+	}
+	slices.Sort(d.AddedParams)
+	slices.Sort(d.RemovedParams)
+	return d
+}
+
+// onlyIn returns the endpoints of a missing from b, sorted by host, path
+// and parameters.
+func onlyIn(a, b map[string]Endpoint) []Endpoint {
+	endpoints := []Endpoint{}
+	for key, e := range a {
+		if _, ok := b[key]; !ok {
+			endpoints = append(endpoints, e)
+		}
+	}
+	slices.SortFunc(endpoints, func(x, y Endpoint) int {
+		return strings.Compare(x.key(), y.key())
+	})
+	return endpoints
+}
+
+func paramSet(endpoints map[string]Endpoint) map[string]struct{} {
+	params := make(map[string]struct{})
+	for _, e := range endpoints {
+		for _, param := range e.Params {
+			params[param] = struct{}{}
+		}
+	}
+	return params
+}
@@ -0,0 +1,115 @@
+package uro
+
+import "strings"
+
+// Decision reasons (see Decision.Reason).
+const (
+	ReasonNewPath   = "new-path"   // kept: the path was not seen before
+	ReasonNewParams = "new-params" // kept: the URL brings new parameters
+	ReasonNew       = "new"        // kept by a non-classic deduplication strategy
+	ReasonEmpty     = "empty"      // dropped: empty line
+	ReasonInvalid   = "invalid"    // dropped: the URL can't be parsed or has no host
+	ReasonFilter    = "filter"     // dropped by a filter (see Decision.Filter)
+	ReasonCluster   = "cluster"    // dropped: the path is similar to a kept one
+	ReasonPattern   = "pattern"    // dropped: the path collapses into a seen pattern
+	ReasonParams    = "params"     // dropped: a known path without new parameters
+	ReasonDuplicate = "duplicate"  // dropped by the deduplication strategy
+)
+
+// Decision describes why a URL was kept or dropped.
+type Decision struct {
+	// URL is the processed input.
+	URL string
+	// Kept reports whether the URL was kept.
+	Kept bool
+	// Output is the kept URL in the configured output form.
+	Output string
+	// Reason is the rule that made the decision (one of the Reason* constants).
+	Reason string
+	// Filter is the filter that dropped the URL: "blacklist", "whitelist",
+	// "removecontent", "vuln", "hasparams", ... Set only with ReasonFilter.
+	Filter string
+	// Match is what the URL matched: the extension for extension filters,
+	// the content prefix or slug for "removecontent", the path pattern for
+	// ReasonPattern.
+	Match string
+	// DuplicateOf is the earlier kept URL (as given in the input) that made
+	// this one a duplicate.
+	DuplicateOf string
+
+	host string // deduplication bucket, for Stats
+	raw  string // normalized input of a kept URL, for template counts
+}
+
+// String formats the decision as "kept reason=new-path" or
+// "dropped reason=pattern match=/users/\d+ duplicate-of=https://...".
+func (d Decision) String() string {
+	var b strings.Builder
+	if d.Kept {
+		b.WriteString("kept")
+	} else {
+		b.WriteString("dropped")
+	}
+	b.WriteString(" reason=" + d.Reason)
+	if d.Filter != "" {
+		b.WriteString(" filter=" + d.Filter)
+	}
+	if d.Match != "" {
+		b.WriteString(" match=" + d.Match)
+	}
+	if d.DuplicateOf != "" {
+		b.WriteString(" duplicate-of=" + d.DuplicateOf)
+	}
+	return b.String()
+}
+
+// ProcessExplain processes a URL like Process and returns the decision
+// made for it. This method is thread-safe.
+func (p *Processor) ProcessExplain(rawURL string) Decision {
+	return p.process(rawURL)
+}
+
+// explainer is implemented by the built-in dedupers to report why
+// a candidate was kept or dropped.
+type explainer interface {
+	explain(c *Candidate, d *Decision) bool
+}
+
+// keep runs the deduper and records its reason in the decision.
+func (p *Processor) keep(c *Candidate, d *Decision) bool {
+	if e, ok := p.deduper.(explainer); ok {
+		return e.explain(c, d)
+	}
+	p.dedupMu.Lock()
+	kept := p.deduper.Keep(c)
+	p.dedupMu.Unlock()
+	if kept {
+		d.Reason = ReasonNew
+		return true
+	}
+	d.Reason = ReasonDuplicate
+	return false
+}
+
+// coveringURL returns the first kept URL of the path whose parameters
+// include all novelty keys of params, or the first URL of the path.
+func (p *Processor) coveringURL(pe *pathEntry, params Params) string {
+	keys := p.paramKeys(params)
+	for _, e := range pe.entries {
+		have := make(map[string]struct{})
+		for _, key := range p.paramKeys(e.params) {
+			have[key] = struct{}{}
+		}
+		covered := true
+		for _, key := range keys {
+			if _, ok := have[key]; !ok {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return e.raw
+		}
+	}
+	return pe.raw
+}
@@ -0,0 +1,156 @@
+package uro
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Preferred host representatives (see HostRules.Prefer).
+const (
+	PreferFirst = "first"
+	PreferHTTPS = "https"
+	PreferHTTP  = "http"
+	PreferWWW   = "www"
+	PreferApex  = "apex"
+)
+
+// HostRules configures which hosts are treated as the same deduplication bucket.
+// By default every scheme://host:port is a separate bucket.
+type HostRules struct {
+	// IgnoreScheme puts http:// and https:// URLs of a host into one bucket.
+	IgnoreScheme bool
+
+	// FoldWWW treats www.example.com as example.com.
+	FoldWWW bool
+
+	// FoldPorts ignores ports: example.com:8080 is the same as example.com.
+	FoldPorts bool
+
+	// IDN converts internationalized host names to punycode, so that
+	// пример.рф and xn--e1afmkfd.xn--p1ai are the same host.
+	IDN bool
+
+	// Aliases maps hosts to the host they are equivalent to,
+	// e.g. {"cdn.example.com": "example.com"}.
+	Aliases map[string]string
+
+	// Prefer selects the host shown for a bucket with several equivalent hosts:
+	//   - "first" (default): the first host seen
+	//   - "https" / "http": a host with this scheme
+	//   - "www": a www. host
+	//   - "apex": a host without www.
+	// In streaming mode URLs are written with the representative known at that time.
+	Prefer string
+}
+
+// enabled reports whether any host equivalence rule is set.
+func (r *HostRules) enabled() bool {
+	return r.IgnoreScheme || r.FoldWWW || r.FoldPorts || r.IDN || len(r.Aliases) > 0
+}
+
+// ParseHostAliases parses "alias=host" pairs (e.g. "cdn.example.com=example.com")
+// into a map suitable for HostRules.Aliases.
+func ParseHostAliases(pairs []string) map[string]string {
+	aliases := make(map[string]string)
+	for _, pair := range pairs {
+		for _, part := range strings.Split(pair, ",") {
+			alias, host, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if ok && alias != "" && host != "" {
+				aliases[strings.ToLower(alias)] = strings.ToLower(host)
+			}
+		}
+	}
+	return aliases
+}
+
+// hostKey returns the deduplication bucket of the URL host.
+// Without host rules it is scheme://host as given.
+func (p *Processor) hostKey(u *url.URL) string {
+	host := u.Scheme + "://" + u.Host
+	rules := &p.opts.HostRules
+	if !rules.enabled() {
+		return host
+	}
+
+	name, port := u.Hostname(), u.Port()
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if addr, err := netip.ParseAddr(name); err == nil {
+		name = addr.String()
+	} else if rules.IDN {
+		if ascii, err := idna.Lookup.ToASCII(name); err == nil {
+			name = ascii
+		}
+	}
+	if alias, ok := rules.Aliases[name]; ok {
+		name = alias
+	}
+	if rules.FoldWWW {
+		name = strings.TrimPrefix(name, "www.")
+	}
+	if alias, ok := rules.Aliases[name]; ok {
+		name = alias
+	}
+
+	if !rules.FoldPorts && port != "" {
+		name = net.JoinHostPort(name, port)
+	} else if strings.Contains(name, ":") {
+		name = "[" + name + "]"
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if rules.IgnoreScheme {
+		scheme = "*"
+	}
+	return scheme + "://" + name
+}
+
+// updateHostRep records host as the representative of its bucket
+// if the bucket has none yet or if host is preferred over the current one.
+// The caller must hold the shard lock of the bucket.
+func (p *Processor) updateHostRep(key, host string) {
+	reps := p.shardFor(key).hostReps
+	current, ok := reps[key]
+	if !ok {
+		reps[key] = host
+		return
+	}
+	if current != host && preferHost(p.opts.HostRules.Prefer, host, current) {
+		reps[key] = host
+	}
+}
+
+// displayHost returns the representative host of a bucket.
+// The caller must hold the shard lock of the bucket.
+func (p *Processor) displayHost(key string) string {
+	if rep, ok := p.shardFor(key).hostReps[key]; ok {
+		return rep
+	}
+	return key
+}
+
+// preferHost reports whether candidate is a better representative than current.
+func preferHost(prefer, candidate, current string) bool {
+	hasWWW := func(host string) bool {
+		if i := strings.Index(host, "://"); i >= 0 {
+			host = host[i+3:]
+		}
+		return strings.HasPrefix(strings.ToLower(host), "www.")
+	}
+
+	switch strings.ToLower(prefer) {
+	case PreferHTTPS:
+		return strings.HasPrefix(candidate, "https://") && !strings.HasPrefix(current, "https://")
+	case PreferHTTP:
+		return strings.HasPrefix(candidate, "http://") && !strings.HasPrefix(current, "http://")
+	case PreferWWW:
+		return hasWWW(candidate) && !hasWWW(current)
+	case PreferApex:
+		return !hasWWW(candidate) && hasWWW(current)
+	default:
+		return false
+	}
+}
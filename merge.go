@@ -0,0 +1,163 @@
+package uro
+
+import (
+	"errors"
+	"sort"
+)
+
+// Merge folds the state of other into p, as if other's input had been
+// processed by p after its own: URLs kept by other are replayed through p
+// in the order other kept them, together with the URLs it dropped as
+// pattern duplicates although they had parameters (their path may be known
+// to p, so that the parameters make them new), and then the parameters,
+// patterns and content prefixes other has seen are added to p. Other drops
+// can't turn into keeps, so for the built-in strategies merging shards in
+// input order keeps the same URLs as one sequential pass. With Cluster,
+// all dropped URLs are replayed, as cluster matches change the clusters.
+// Both processors must use the same options. other is not modified.
+// Merge fails in low-memory mode.
+func (p *Processor) Merge(other *Processor) error {
+	if other == p {
+		return nil
+	}
+	if p.lowMemory || other.lowMemory {
+		return errors.New("uro: processors can't be merged in low-memory mode")
+	}
+
+	other.lockAll()
+	urls := other.replayURLs()
+	stats := other.totalStats()
+	baseSeq := other.baseSeq
+	other.unlockAll()
+
+	// A replayed drop is counted again, so it leaves the count of the
+	// URL it was a duplicate of in other
+	dropsOf := make(map[string]int64)
+	for _, u := range urls {
+		if u.drop != nil {
+			dropsOf[u.drop.duplicateOf]++
+		}
+	}
+
+	// Replay with the same rules decide applies
+	for _, u := range urls {
+		d := p.process(u.raw)
+		if u.drop != nil {
+			continue
+		}
+		if n, ok := other.collapsed.load(u.raw); ok {
+			p.collapse(&d, n.Load()-1-dropsOf[u.raw])
+		}
+	}
+
+	other.paramsSeen.each(func(param string, _ struct{}) {
+		p.paramsSeen.add(param, struct{}{})
+	})
+	other.patternsSeen.each(func(key string, pattern Pattern) {
+		p.patternsSeen.loadOrStore(key, pattern)
+	})
+	other.paramNames.each(func(name string, _ struct{}) {
+		p.paramNames.add(name, struct{}{})
+	})
+	for _, prefix := range other.contentPrefixes.load() {
+		p.contentPrefixes.add(prefix)
+	}
+
+	p.lockAll()
+	defer p.unlockAll()
+	p.mergeStats(&stats, urls, baseSeq)
+	return nil
+}
+
+// droppedURL is a URL dropped as a pattern duplicate although it had
+// parameters (another processor may know its path, so that they are new),
+// or with Cluster any dropped URL (cluster matches change the clusters).
+// Merge replays it.
+type droppedURL struct {
+	seq         int64
+	host        string
+	raw         string
+	reason      string
+	duplicateOf string
+}
+
+// logDrop remembers a dropped URL for Merge if it may be kept in another
+// processor. The caller must hold the shard lock.
+func (p *Processor) logDrop(s *shard, pu *preparedURL, d *Decision) {
+	if p.lowMemory || (!p.opts.Cluster && (len(pu.params) == 0 || d.Reason != ReasonPattern)) {
+		return
+	}
+	seq := pu.seq
+	if seq == 0 {
+		seq = p.seq.Add(1)
+	}
+	s.drops = append(s.drops, droppedURL{seq: seq, host: pu.host, raw: pu.raw, reason: d.Reason, duplicateOf: d.DuplicateOf})
+}
+
+// replayURL is a URL Merge replays: a kept one, or a logged drop.
+type replayURL struct {
+	seq  int64
+	raw  string
+	drop *droppedURL
+}
+
+// replayURLs returns the input text of all stored URLs and logged drops in
+// the order they were processed, including those of a loaded state.
+// The caller must hold all shard locks.
+func (p *Processor) replayURLs() []replayURL {
+	var urls []replayURL
+	for _, sh := range p.shards {
+		for _, paths := range sh.urlMap {
+			for _, pe := range paths {
+				// The path itself was kept without parameters first
+				if len(pe.entries) == 0 || pe.seq < pe.entries[0].seq {
+					urls = append(urls, replayURL{seq: pe.seq, raw: pe.raw})
+				}
+				for _, e := range pe.entries {
+					urls = append(urls, replayURL{seq: e.seq, raw: e.raw})
+				}
+			}
+		}
+		for i := range sh.drops {
+			urls = append(urls, replayURL{seq: sh.drops[i].seq, raw: sh.drops[i].raw, drop: &sh.drops[i]})
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].seq < urls[j].seq })
+	return urls
+}
+
+// mergeStats adds the counters of another processor. Its kept URLs and
+// logged drops were already counted when they were replayed; drops of a
+// state it loaded (up to baseSeq) are not in its counters.
+// The caller must hold all shard locks.
+func (p *Processor) mergeStats(o *Stats, replayed []replayURL, baseSeq int64) {
+	for _, u := range replayed {
+		if d := u.drop; d != nil && d.seq > baseSeq {
+			o.Input--
+			o.Duplicates[d.reason]--
+			h := o.Hosts[d.host]
+			h.Input--
+			h.Duplicates--
+			o.Hosts[d.host] = h
+		}
+	}
+
+	s := &p.shards[0].stats
+	s.Input += o.Input - o.Kept
+	s.Empty += o.Empty
+	s.Invalid += o.Invalid
+	for name, n := range o.Filtered {
+		s.Filtered[name] += n
+	}
+	for reason, n := range o.Duplicates {
+		s.Duplicates[reason] += n
+	}
+	for host, oh := range o.Hosts {
+		hosts := p.shardFor(host).stats.Hosts
+		h := hosts[host]
+		h.Input += oh.Input - oh.Kept
+		h.Filtered += oh.Filtered
+		h.Duplicates += oh.Duplicates
+		hosts[host] = h
+	}
+}
@@ -0,0 +1,110 @@
+package uro
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// processAll returns a processor that has processed urls.
+func processAll(opts *Options, urls []string) *Processor {
+	p := NewProcessor(opts)
+	for _, u := range urls {
+		p.Process(u)
+	}
+	return p
+}
+
+func TestMergeMatchesSequential(t *testing.T) {
+	partA := []string{"https://h.com/users/2"}
+	partB := []string{"https://h.com/users/1?x=1", "https://h.com/users/2?y=1"}
+
+	seq := processAll(nil, append(slices.Clone(partA), partB...))
+	want := []string{"https://h.com/users/2?y=1"}
+	if got := seq.Results(); !slices.Equal(got, want) {
+		t.Fatalf("sequential Results() = %q, want %q", got, want)
+	}
+
+	a, b := processAll(nil, partA), processAll(nil, partB)
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Results(); !slices.Equal(got, want) {
+		t.Errorf("merged Results() = %q, want %q", got, want)
+	}
+}
+
+func TestMergeShardsMatchSequential(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	urls := make([]string, 3000)
+	for i := range urls {
+		words := []string{"red", "blue", "shoe", "hat"}
+		u := fmt.Sprintf("https://h%d.com/%s/%d", rng.IntN(3), []string{"users", "items", "blog/posts"}[rng.IntN(3)], rng.IntN(40))
+		if rng.IntN(2) == 0 {
+			u = fmt.Sprintf("https://h%d.com/shop/%s-%s-%d", rng.IntN(3), words[rng.IntN(4)], words[rng.IntN(4)], rng.IntN(5))
+		}
+		if n := rng.IntN(3); n > 0 {
+			u += fmt.Sprintf("?p%d=1", rng.IntN(6))
+			if n > 1 {
+				u += fmt.Sprintf("&q%d=2", rng.IntN(4))
+			}
+		}
+		urls[i] = u
+	}
+	parts := [][]string{urls[:700], urls[700:1900], urls[1900:]}
+
+	for _, tt := range []struct {
+		name string
+		opts Options
+	}{
+		{"default", Options{}},
+		{"host params", Options{ParamScope: ScopeHost}},
+		{"path params", Options{ParamScope: ScopePath, PatternScope: ScopeHost}},
+		{"cluster", Options{Cluster: true}},
+		{"keys", Options{Dedup: DedupKeys}},
+	} {
+		opts := tt.opts
+		t.Run(tt.name, func(t *testing.T) {
+			want := processAll(&opts, urls).Results()
+
+			// Merge one by one, and the last two parts first
+			chain := processAll(&opts, parts[0])
+			for _, part := range parts[1:] {
+				if err := chain.Merge(processAll(&opts, part)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := chain.Results(); !slices.Equal(got, want) {
+				t.Errorf("chained merge kept %d URLs, sequential %d", len(got), len(want))
+			}
+
+			tree := processAll(&opts, parts[1])
+			tree.Merge(processAll(&opts, parts[2]))
+			root := processAll(&opts, parts[0])
+			root.Merge(tree)
+			if got := root.Results(); !slices.Equal(got, want) {
+				t.Errorf("tree merge kept %d URLs, sequential %d", len(got), len(want))
+			}
+
+			// Through saved states, as uro merge does
+			merged := NewProcessor(&opts)
+			for _, part := range parts {
+				var buf bytes.Buffer
+				if err := processAll(&opts, part).Save(&buf); err != nil {
+					t.Fatal(err)
+				}
+				shard := NewProcessor(&opts)
+				if err := shard.Load(strings.NewReader(buf.String())); err != nil {
+					t.Fatal(err)
+				}
+				merged.Merge(shard)
+			}
+			if got := merged.Results(); !slices.Equal(got, want) {
+				t.Errorf("merge of saved states kept %d URLs, sequential %d", len(got), len(want))
+			}
+		})
+	}
+}
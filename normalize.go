@@ -0,0 +1,119 @@
+package uro
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SegmentNormalizer detects variable path segments such as identifiers,
+// hashes or dates. Paths that differ only in such segments collapse into
+// a single pattern, the same way purely numeric segments do.
+type SegmentNormalizer interface {
+	// Name returns the detector name. It is used in Options.Normalizers
+	// and as the placeholder in pattern templates (e.g. "{uuid}").
+	Name() string
+
+	// Match reports whether the path segment is a variable value.
+	Match(segment string) bool
+}
+
+// NewSegmentNormalizer returns a SegmentNormalizer built from a name and a match function.
+func NewSegmentNormalizer(name string, match func(segment string) bool) SegmentNormalizer {
+	return &funcNormalizer{name: name, match: match}
+}
+
+type funcNormalizer struct {
+	name  string
+	match func(string) bool
+}
+
+func (n *funcNormalizer) Name() string              { return n.name }
+func (n *funcNormalizer) Match(segment string) bool { return n.match(segment) }
+
+var (
+	reUUID  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	reULID  = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+	reDate  = regexp.MustCompile(`^\d{4}([-_.])(0?[1-9]|1[0-2])([-_.])(0?[1-9]|[12]\d|3[01])$`)
+	reEmail = regexp.MustCompile(`^[^@\s/]+@[^@\s/]+\.[a-zA-Z]{2,}$`)
+)
+
+// builtinNormalizers lists the built-in detectors in the order they are tried.
+// More specific detectors go first so that e.g. a UUID is not reported as hex.
+var builtinNormalizers = []SegmentNormalizer{
+	NewSegmentNormalizer("uuid", reUUID.MatchString),
+	NewSegmentNormalizer("ulid", reULID.MatchString),
+	NewSegmentNormalizer("date", reDate.MatchString),
+	NewSegmentNormalizer("email", reEmail.MatchString),
+	NewSegmentNormalizer("hex", isHexID),
+	NewSegmentNormalizer("base64", isTokenID),
+}
+
+// setupNormalizers builds the list of active segment normalizers from options.
+func (p *Processor) setupNormalizers() {
+	enabled := make(map[string]bool)
+	for _, name := range cleanArgs(p.opts.Normalizers) {
+		enabled[name] = true
+	}
+
+	for _, n := range builtinNormalizers {
+		if enabled["all"] || enabled[n.Name()] {
+			p.normalizers = append(p.normalizers, n)
+		}
+	}
+	p.normalizers = append(p.normalizers, p.opts.SegmentNormalizers...)
+}
+
+// normalizeSegment returns the placeholder for a variable path segment,
+// or an empty string if the segment is static.
+func (p *Processor) normalizeSegment(part string) string {
+	if isDigit(part) {
+		return `\d+`
+	}
+	for _, n := range p.normalizers {
+		if n.Match(part) {
+			return "{" + n.Name() + "}"
+		}
+	}
+	return ""
+}
+
+// isHexID reports whether s looks like a hex hash or identifier (16+ hex chars, at least one digit).
+func isHexID(s string) bool {
+	if len(s) < 16 {
+		return false
+	}
+	hasDigit := false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		case (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
+
+// isTokenID reports whether s looks like a base62/base64 token: 8+ alphanumeric chars
+// (optionally with "_" and "=" padding) mixing upper case, lower case and digits.
+func isTokenID(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, c := range strings.TrimRight(s, "=") {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c == '_':
+		default:
+			return false
+		}
+	}
+	return upper && lower && digit
+}
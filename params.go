@@ -0,0 +1,235 @@
+package uro
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultIgnoreParams lists tracking and cache-buster parameters ignored
+// when Options.IgnoreTracking is set. Entries are glob patterns.
+var DefaultIgnoreParams = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "msclkid", "yclid", "igshid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "ref_src",
+	"_", "v", "cb", "ts", "timestamp", "cachebuster", "nocache", "rnd", "rand",
+}
+
+// Param is a single query parameter.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is an ordered list of query parameters.
+// Repeated parameters (a=1&a=2) are kept in source order.
+type Params []Param
+
+// ParseParams parses a raw query string into an ordered parameter list.
+// Keys and values are kept as they appear (percent-encoded); pairs with an
+// empty key are skipped.
+func ParseParams(query string) Params {
+	if query == "" {
+		return nil
+	}
+	var params Params
+	for _, pair := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key != "" {
+			params = append(params, Param{Key: key, Value: value})
+		}
+	}
+	return params
+}
+
+// Get returns the first value of the parameter, or an empty string.
+func (ps Params) Get(key string) string {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Values returns all values of the parameter in source order.
+func (ps Params) Values(key string) []string {
+	var values []string
+	for _, p := range ps {
+		if p.Key == key {
+			values = append(values, p.Value)
+		}
+	}
+	return values
+}
+
+// Has reports whether the parameter is present.
+func (ps Params) Has(key string) bool {
+	for _, p := range ps {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the distinct parameter names in order of first occurrence.
+func (ps Params) Keys() []string {
+	keys := make([]string, 0, len(ps))
+	seen := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		if _, ok := seen[p.Key]; !ok {
+			seen[p.Key] = struct{}{}
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// Repeated returns the names of parameters that occur more than once,
+// excluding array notation (ids[]=1&ids[]=2). Such URLs are HTTP parameter
+// pollution (HPP) candidates.
+func (ps Params) Repeated() []string {
+	counts := make(map[string]int, len(ps))
+	var repeated []string
+	for _, p := range ps {
+		counts[p.Key]++
+		if counts[p.Key] == 2 && !strings.HasSuffix(p.Key, "[]") {
+			repeated = append(repeated, p.Key)
+		}
+	}
+	return repeated
+}
+
+// splitRoute splits the parameters into the server ones and the last n,
+// which come from a hash route.
+func (ps Params) splitRoute(n int) (Params, Params) {
+	n = min(n, len(ps))
+	return ps[:len(ps)-n], ps[len(ps)-n:]
+}
+
+// Encode returns the parameters as a query string without the leading "?".
+func (ps Params) Encode() string {
+	pairs := make([]string, 0, len(ps))
+	for _, p := range ps {
+		pairs = append(pairs, p.Key+"="+p.Value)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// repeatedKey is appended to the name of a repeated parameter to form its
+// extra novelty key.
+const repeatedKey = "\x00repeated"
+
+var reBracketKey = regexp.MustCompile(`^([^\[\]]+)\[[^\]]*\](\[[^\]]*\])*$`)
+
+// GroupBracketKey maps bracket-notation parameter names to a group name:
+// user[name], user[role] and user[a][b] become user[*], ids[] becomes ids[*].
+// Other names are returned unchanged.
+func GroupBracketKey(key string) string {
+	if m := reBracketKey.FindStringSubmatch(key); m != nil {
+		return m[1] + "[*]"
+	}
+	return key
+}
+
+// paramKeys returns the distinct novelty keys for a set of parameters.
+// In value-aware mode each key is the parameter name paired with its value class.
+// With KeepRepeated, repeated parameters add an extra key so that HPP
+// candidates are not dropped.
+func (p *Processor) paramKeys(params Params) []string {
+	keys := make([]string, 0, len(params))
+	seen := make(map[string]struct{}, len(params))
+	add := func(key string) {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	for _, param := range params {
+		if p.isIgnoredParam(param.Key) {
+			continue
+		}
+		name := param.Key
+		if p.opts.GroupBrackets {
+			name = GroupBracketKey(name)
+		}
+		if p.opts.ValueAware {
+			add(name + "=" + ClassifyValue(param.Value))
+		} else {
+			add(name)
+		}
+	}
+	if !p.opts.KeepRepeated {
+		return keys
+	}
+	for _, key := range params.Repeated() {
+		if p.isIgnoredParam(key) {
+			continue
+		}
+		if p.opts.GroupBrackets {
+			key = GroupBracketKey(key)
+		}
+		add(key + repeatedKey)
+	}
+	return keys
+}
+
+// isIgnoredParam reports whether a parameter is excluded from novelty checks.
+func (p *Processor) isIgnoredParam(name string) bool {
+	if p.opts.IgnoreTracking && matchAny(DefaultIgnoreParams, name) {
+		return true
+	}
+	return matchAny(p.opts.IgnoreParams, name)
+}
+
+// projectParams applies StripIgnored and KeepParams to the parameters.
+func (p *Processor) projectParams(params Params) Params {
+	if !p.opts.StripIgnored && len(p.opts.KeepParams) == 0 {
+		return params
+	}
+	kept := params[:0:0]
+	for _, param := range params {
+		if p.opts.StripIgnored && p.isIgnoredParam(param.Key) {
+			continue
+		}
+		if len(p.opts.KeepParams) > 0 && !matchAny(p.opts.KeepParams, param.Key) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	return kept
+}
+
+// noveltyParams returns the parameters that take part in novelty checks.
+func (p *Processor) noveltyParams(params Params) Params {
+	kept := params[:0:0]
+	for _, param := range params {
+		if !p.isIgnoredParam(param.Key) {
+			kept = append(kept, param)
+		}
+	}
+	return kept
+}
+
+// matchAny reports whether name matches one of the glob patterns (case-insensitive).
+func matchAny(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPairs returns key=value pairs of params sorted lexicographically.
+func sortedPairs(params Params) []string {
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, p.Key+"="+p.Value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
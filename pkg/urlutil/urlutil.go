@@ -5,7 +5,12 @@ import (
 	"strings"
 )
 
-// ParamsToMap преобразует query string в map
+// ParamsToMap преобразует query string в map. Преобразование с потерями:
+// для повторяющегося параметра (a=1&a=2) остаётся только последнее значение,
+// а порядок параметров теряется.
+//
+// Deprecated: используйте uro.ParseParams, который сохраняет все значения
+// и их порядок.
 func ParamsToMap(query string) map[string]string {
 	result := make(map[string]string)
 	if query == "" {
@@ -0,0 +1,191 @@
+package uro
+
+import (
+	"iter"
+	"sort"
+)
+
+// Output orders for Results and WriteResults (see Options.Order).
+const (
+	OrderInput = "input"
+	OrderLex   = "lex"
+	OrderHost  = "host"
+)
+
+// Output forms of kept URLs (see Options.Output).
+const (
+	OutputCanonical = "canonical"
+	OutputOriginal  = "original"
+	OutputTemplate  = "template"
+)
+
+// pathEntry holds the kept URLs of one host and path.
+// A path kept without parameters has no entries.
+type pathEntry struct {
+	seq     int64
+	raw     string
+	entries []*urlEntry
+}
+
+// urlEntry is a kept URL with parameters.
+type urlEntry struct {
+	seq         int64
+	raw         string
+	params      Params
+	routeParams int // trailing parameters from a hash route
+}
+
+// result is a single output URL with its position in the input.
+type result struct {
+	seq         int64
+	host        string
+	path        string
+	query       string // sort key, set only for OrderLex
+	raw         string
+	params      Params
+	routeParams int
+}
+
+// Entry is a kept URL split into its parts (see Processor.Entries).
+type Entry struct {
+	// URL is the URL in the configured output form, as returned by Results.
+	URL string
+	// Host is the scheme://host of the URL; with HostRules, the host shown
+	// for its bucket.
+	Host string
+	// Path is the URL path.
+	Path string
+	// Params are the query parameters in their input order.
+	Params Params
+	// Pattern is the collapsed path pattern the URL belongs to
+	// (e.g. /users/\d+, see Pattern), or empty if the path has no
+	// variable segments.
+	Pattern string
+	// Original is the input line the URL was kept from.
+	Original string
+}
+
+// Entries returns an iterator over the deduplicated URLs as structured
+// entries, in the order of Results. When iteration starts, an index of all
+// kept URLs is collected and sorted, so Entries does not lower peak memory
+// much below Results: only the output strings and entries are built one at
+// a time. The processor may be used inside the loop. In streaming mode, the
+// iterator yields nothing.
+func (p *Processor) Entries() iter.Seq[Entry] {
+	return func(yield func(Entry) bool) {
+		if p.streaming {
+			return
+		}
+
+		p.lockAll()
+		results := p.collectResults()
+		p.unlockAll()
+
+		for _, r := range results {
+			if !yield(p.entry(r)) {
+				return
+			}
+		}
+	}
+}
+
+// entry builds the Entry of a result.
+func (p *Processor) entry(r result) Entry {
+	pattern, _ := p.createPattern(r.path)
+	return Entry{
+		URL:      p.format(r),
+		Host:     r.host,
+		Path:     r.path,
+		Params:   r.params,
+		Pattern:  pattern,
+		Original: r.raw,
+	}
+}
+
+// format returns the URL in the configured output form.
+func (p *Processor) format(r result) string {
+	switch p.output {
+	case OutputOriginal:
+		return r.raw
+	case OutputTemplate:
+		return p.template(r)
+	}
+	if p.opts.HashRoutes {
+		// The server query goes before the route, the route query after it
+		if path, route := splitRoute(r.path); route != "" {
+			server, routeParams := r.params.splitRoute(r.routeParams)
+			return r.host + path + mapToQuery(server) + route + mapToQuery(routeParams)
+		}
+	}
+	return r.host + r.path + mapToQuery(r.params)
+}
+
+// collectResults returns the stored URLs in the configured order.
+// The caller must hold all shard locks.
+func (p *Processor) collectResults() []result {
+	var results []result
+	hostSeq := make(map[string]int64)
+	for _, sh := range p.shards {
+		for key, paths := range sh.urlMap {
+			host := p.displayHost(key)
+			first := int64(-1)
+			for path, pe := range paths {
+				if first < 0 || pe.seq < first {
+					first = pe.seq
+				}
+				if len(pe.entries) == 0 {
+					results = append(results, result{seq: pe.seq, host: host, path: path, raw: pe.raw})
+					continue
+				}
+				for _, e := range pe.entries {
+					r := result{
+						seq:         e.seq,
+						host:        host,
+						path:        path,
+						raw:         e.raw,
+						params:      e.params,
+						routeParams: e.routeParams,
+					}
+					if p.order == OrderLex {
+						r.query = mapToQuery(e.params)
+					}
+					results = append(results, r)
+				}
+			}
+			hostSeq[host] = first
+		}
+	}
+
+	// URLs of a loaded state were reported by the run that saved it
+	if p.baseSeq > 0 {
+		fresh := results[:0]
+		for _, r := range results {
+			if r.seq > p.baseSeq {
+				fresh = append(fresh, r)
+			}
+		}
+		results = fresh
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch p.order {
+		case OrderLex:
+			if a.host != b.host {
+				return a.host < b.host
+			}
+			if a.path != b.path {
+				return a.path < b.path
+			}
+			if a.query != b.query {
+				return a.query < b.query
+			}
+		case OrderHost:
+			if a.host != b.host {
+				return hostSeq[a.host] < hostSeq[b.host]
+			}
+		}
+		return a.seq < b.seq
+	})
+	return results
+}
@@ -0,0 +1,74 @@
+package uro
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultSessionTokens lists session parameter names removed when
+// Options.StripSessions is set. Names are matched case-insensitively
+// and may contain glob patterns.
+var DefaultSessionTokens = []string{
+	"jsessionid", "phpsessid", "aspsessionid*", "asp.net_sessionid",
+	"sessionid", "session_id", "sid", "cfid", "cftoken", "zenid", "oscsid",
+}
+
+// reCookieless matches ASP.NET cookieless session segments: (S(...)), (S(...)A(...)).
+var reCookieless = regexp.MustCompile(`^\(([A-Z]\([^()]*\))+\)$`)
+
+// splitMatrix removes matrix parameters (/item;id=5;color=red) and session
+// tokens from the path. Matrix parameters are returned in path order if
+// MatrixParams is set; otherwise non-session ones stay in the path.
+func (p *Processor) splitMatrix(urlPath string) (string, Params) {
+	if !strings.ContainsAny(urlPath, ";(") {
+		return urlPath, nil
+	}
+
+	var params Params
+	segments := strings.Split(urlPath, "/")
+	kept := segments[:0]
+	for _, segment := range segments {
+		if p.opts.StripSessions && reCookieless.MatchString(segment) {
+			continue
+		}
+
+		base, rest, found := strings.Cut(segment, ";")
+		if !found {
+			kept = append(kept, segment)
+			continue
+		}
+		for _, piece := range strings.Split(rest, ";") {
+			key, value, _ := strings.Cut(piece, "=")
+			switch {
+			case key == "":
+			case p.opts.StripSessions && p.isSessionToken(key):
+			case p.opts.MatrixParams:
+				params = append(params, Param{Key: key, Value: value})
+			default:
+				base += ";" + piece
+			}
+		}
+		kept = append(kept, base)
+	}
+	return strings.Join(kept, "/"), params
+}
+
+// stripSessions removes session token parameters.
+func (p *Processor) stripSessions(params Params) Params {
+	kept := params[:0:0]
+	for _, param := range params {
+		if !p.isSessionToken(param.Key) {
+			kept = append(kept, param)
+		}
+	}
+	return kept
+}
+
+// isSessionToken reports whether a parameter name is a session token.
+func (p *Processor) isSessionToken(name string) bool {
+	tokens := p.opts.SessionTokens
+	if tokens == nil {
+		tokens = DefaultSessionTokens
+	}
+	return matchAny(tokens, name)
+}
@@ -0,0 +1,151 @@
+package uro
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// numShards is the number of host shards the processor state is split into.
+const numShards = 64
+
+// shard holds the state of the hosts hashed to it. URLs of different shards
+// are deduplicated concurrently, URLs of one shard one at a time.
+type shard struct {
+	mu       sync.Mutex
+	urlMap   map[string]map[string]*pathEntry
+	clusters map[string][]*pathCluster
+	hostReps map[string]string
+	drops    []droppedURL // see Processor.logDrop
+	stats    Stats
+}
+
+func newShard() *shard {
+	return &shard{
+		urlMap:   make(map[string]map[string]*pathEntry),
+		clusters: make(map[string][]*pathCluster),
+		hostReps: make(map[string]string),
+		stats:    newStats(),
+	}
+}
+
+// shardFor returns the shard of a host bucket.
+func (p *Processor) shardFor(host string) *shard {
+	return p.shards[maphash.String(p.seed, host)%numShards]
+}
+
+// commitKey returns the key that routes a URL to a committer in parallel
+// mode. URLs with the same key are committed in input order, so it is the
+// pattern group of the host if patterns are scoped, and the host otherwise.
+func (p *Processor) commitKey(host string) string {
+	if group := p.hostGroup(host); group != "" {
+		return group
+	}
+	return host
+}
+
+// lockAll locks all shards for operations on the whole state.
+func (p *Processor) lockAll() {
+	for _, s := range p.shards {
+		s.mu.Lock()
+	}
+}
+
+func (p *Processor) unlockAll() {
+	for _, s := range p.shards {
+		s.mu.Unlock()
+	}
+}
+
+// concurrentMap is a map safe for concurrent use by all shards. It suits
+// keys that are written once and then only read (seen parameters, patterns):
+// reading an existing key takes no lock.
+type concurrentMap[V any] struct {
+	m sync.Map
+}
+
+// loadOrStore returns the existing value for the key and true, or stores
+// value and returns it and false.
+func (c *concurrentMap[V]) loadOrStore(key string, value V) (V, bool) {
+	actual, loaded := c.m.LoadOrStore(key, value)
+	return actual.(V), loaded
+}
+
+// add stores the key if it is absent and reports whether it was absent.
+func (c *concurrentMap[V]) add(key string, value V) bool {
+	if _, ok := c.m.Load(key); ok {
+		return false
+	}
+	_, loaded := c.m.LoadOrStore(key, value)
+	return !loaded
+}
+
+func (c *concurrentMap[V]) load(key string) (V, bool) {
+	value, ok := c.m.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return value.(V), true
+}
+
+func (c *concurrentMap[V]) has(key string) bool {
+	_, ok := c.m.Load(key)
+	return ok
+}
+
+// each calls fn for every key and value, in no particular order.
+func (c *concurrentMap[V]) each(fn func(key string, value V)) {
+	c.m.Range(func(key, value any) bool {
+		fn(key.(string), value.(V))
+		return true
+	})
+}
+
+func (c *concurrentMap[V]) len() int {
+	n := 0
+	c.m.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (c *concurrentMap[V]) clear() {
+	c.m.Clear()
+}
+
+// prefixList is a copy-on-write list of content prefixes: reads take no lock,
+// additions are serialized.
+type prefixList struct {
+	mu   sync.Mutex
+	list atomic.Pointer[[]string]
+}
+
+func (l *prefixList) load() []string {
+	if list := l.list.Load(); list != nil {
+		return *list
+	}
+	return nil
+}
+
+// add appends the prefix unless it is already in the list.
+func (l *prefixList) add(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.load()
+	for _, p := range old {
+		if p == prefix {
+			return
+		}
+	}
+	list := make([]string, len(old), len(old)+1)
+	copy(list, old)
+	list = append(list, prefix)
+	l.list.Store(&list)
+}
+
+func (l *prefixList) reset() {
+	l.list.Store(nil)
+}
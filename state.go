@@ -0,0 +1,211 @@
+package uro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// StateVersion is the version of the state format written by Save.
+const StateVersion = 1
+
+// state is the serialized form of the processor state.
+type state struct {
+	Version         int               `json:"version"`
+	Seq             int64             `json:"seq"`
+	Paths           []statePath       `json:"paths"`
+	Params          []string          `json:"params"`
+	Patterns        []Pattern         `json:"patterns"`
+	ContentPrefixes []string          `json:"content_prefixes"`
+	HostReps        map[string]string `json:"host_reps,omitempty"`
+	Clusters        []stateCluster    `json:"clusters,omitempty"`
+	Drops           []stateDrop       `json:"drops,omitempty"`  // replayed by Merge
+	Keys            map[string]string `json:"keys,omitempty"`   // exact, keys and template strategies
+	Counts          map[string]int64  `json:"counts,omitempty"` // template output
+}
+
+type statePath struct {
+	Host    string       `json:"host"`
+	Path    string       `json:"path"`
+	Seq     int64        `json:"seq"`
+	Raw     string       `json:"raw"`
+	Entries []stateEntry `json:"entries,omitempty"`
+}
+
+type stateEntry struct {
+	Seq         int64  `json:"seq"`
+	Raw         string `json:"raw"`
+	Params      Params `json:"params"`
+	RouteParams int    `json:"route_params,omitempty"`
+}
+
+type stateDrop struct {
+	Seq         int64  `json:"seq"`
+	Host        string `json:"host"`
+	Raw         string `json:"raw"`
+	Reason      string `json:"reason"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+type stateCluster struct {
+	Key      string     `json:"key"`
+	Tokens   [][]string `json:"tokens"`
+	Variable [][]bool   `json:"variable"`
+	Raw      string     `json:"raw"`
+}
+
+// Save writes the processor state (kept URLs, seen parameters, patterns,
+// content prefixes, host representatives, clusters and the drops Merge
+// replays) to w, so that a later run can continue from it with Load.
+// The state of a custom Deduper is not saved. Save fails in low-memory mode.
+func (p *Processor) Save(w io.Writer) error {
+	if p.lowMemory {
+		return errors.New("uro: state can't be saved in low-memory mode")
+	}
+
+	p.lockAll()
+	s := p.snapshot()
+	p.unlockAll()
+
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Load replaces the processor state with one written by Save.
+// It must be loaded with the same options it was saved with.
+// URLs from the loaded state count as already reported: Results and
+// WriteResults return only URLs kept after Load, and duplicates of loaded
+// URLs are dropped. Stats start from zero.
+func (p *Processor) Load(r io.Reader) error {
+	var s state
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("uro: cannot read state: %w", err)
+	}
+	if s.Version != StateVersion {
+		return fmt.Errorf("uro: unsupported state version %d", s.Version)
+	}
+
+	p.lockAll()
+	defer p.unlockAll()
+
+	p.reset()
+	p.restore(&s)
+	p.baseSeq = s.Seq
+	return nil
+}
+
+// snapshot returns the serializable state. The caller must hold all shard locks.
+func (p *Processor) snapshot() *state {
+	s := &state{
+		Version:         StateVersion,
+		Seq:             p.seq.Load(),
+		Params:          []string{},
+		Patterns:        []Pattern{},
+		ContentPrefixes: p.contentPrefixes.load(),
+		HostReps:        make(map[string]string),
+	}
+
+	for _, sh := range p.shards {
+		for host, paths := range sh.urlMap {
+			for path, pe := range paths {
+				sp := statePath{Host: host, Path: path, Seq: pe.seq, Raw: pe.raw}
+				for _, e := range pe.entries {
+					sp.Entries = append(sp.Entries, stateEntry{Seq: e.seq, Raw: e.raw, Params: e.params, RouteParams: e.routeParams})
+				}
+				s.Paths = append(s.Paths, sp)
+			}
+		}
+		maps.Copy(s.HostReps, sh.hostReps)
+		for _, d := range sh.drops {
+			s.Drops = append(s.Drops, stateDrop{Seq: d.seq, Host: d.host, Raw: d.raw, Reason: d.reason, DuplicateOf: d.duplicateOf})
+		}
+		for key, clusters := range sh.clusters {
+			for _, c := range clusters {
+				s.Clusters = append(s.Clusters, stateCluster{Key: key, Tokens: c.tokens, Variable: c.variable, Raw: c.raw})
+			}
+		}
+	}
+	sort.Slice(s.Paths, func(i, j int) bool { return s.Paths[i].Seq < s.Paths[j].Seq })
+	sort.SliceStable(s.Clusters, func(i, j int) bool { return s.Clusters[i].Key < s.Clusters[j].Key })
+	sort.Slice(s.Drops, func(i, j int) bool { return s.Drops[i].Seq < s.Drops[j].Seq })
+
+	p.paramsSeen.each(func(param string, _ struct{}) {
+		s.Params = append(s.Params, param)
+	})
+	sort.Strings(s.Params)
+
+	p.patternsSeen.each(func(_ string, pattern Pattern) {
+		s.Patterns = append(s.Patterns, pattern)
+	})
+	sort.Slice(s.Patterns, func(i, j int) bool {
+		if s.Patterns[i].Group != s.Patterns[j].Group {
+			return s.Patterns[i].Group < s.Patterns[j].Group
+		}
+		return s.Patterns[i].Pattern < s.Patterns[j].Pattern
+	})
+
+	if p.output == OutputTemplate {
+		s.Counts = make(map[string]int64)
+		p.collapsed.each(func(raw string, n *atomic.Int64) {
+			s.Counts[raw] = n.Load()
+		})
+	}
+	if d, ok := p.deduper.(*keyDeduper); ok {
+		s.Keys = make(map[string]string)
+		d.seen.each(func(key, raw string) {
+			s.Keys[key] = raw
+		})
+	}
+	return s
+}
+
+// restore fills the processor from a loaded state. The caller must hold all shard locks.
+func (p *Processor) restore(s *state) {
+	p.seq.Store(s.Seq)
+	for _, sp := range s.Paths {
+		sh := p.shardFor(sp.Host)
+		if _, ok := sh.urlMap[sp.Host]; !ok {
+			sh.urlMap[sp.Host] = make(map[string]*pathEntry)
+		}
+		pe := &pathEntry{seq: sp.Seq, raw: sp.Raw}
+		for _, e := range sp.Entries {
+			pe.entries = append(pe.entries, &urlEntry{seq: e.Seq, raw: e.Raw, params: e.Params, routeParams: e.RouteParams})
+		}
+		sh.urlMap[sp.Host][sp.Path] = pe
+	}
+	for _, param := range s.Params {
+		p.paramsSeen.add(param, struct{}{})
+	}
+	for _, pattern := range s.Patterns {
+		p.patternsSeen.add(pattern.Group+"\x00"+pattern.Pattern, pattern)
+	}
+	for _, prefix := range s.ContentPrefixes {
+		p.contentPrefixes.add(prefix)
+	}
+	for key, host := range s.HostReps {
+		p.shardFor(key).hostReps[key] = host
+	}
+	for _, d := range s.Drops {
+		sh := p.shardFor(d.Host)
+		sh.drops = append(sh.drops, droppedURL{seq: d.Seq, host: d.Host, raw: d.Raw, reason: d.Reason, duplicateOf: d.DuplicateOf})
+	}
+	for _, c := range s.Clusters {
+		host, _, _ := strings.Cut(c.Key, "\x00")
+		sh := p.shardFor(host)
+		sh.clusters[c.Key] = append(sh.clusters[c.Key], &pathCluster{tokens: c.Tokens, variable: c.Variable, raw: c.Raw})
+	}
+	for raw, n := range s.Counts {
+		count := new(atomic.Int64)
+		count.Store(n)
+		p.collapsed.add(raw, count)
+	}
+	if d, ok := p.deduper.(*keyDeduper); ok {
+		for key, raw := range s.Keys {
+			d.seen.add(key, raw)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package uro
+
+import "maps"
+
+// Stats holds processing counters (see Processor.Stats).
+type Stats struct {
+	// Input is the number of processed lines.
+	Input int64 `json:"input"`
+	// Empty is the number of blank lines.
+	Empty int64 `json:"empty"`
+	// Invalid is the number of lines that could not be parsed as a URL with a host.
+	Invalid int64 `json:"invalid"`
+	// Kept is the number of kept URLs.
+	Kept int64 `json:"kept"`
+	// Filtered counts URLs dropped by each filter ("blacklist", "removecontent", ...).
+	Filtered map[string]int64 `json:"filtered"`
+	// Duplicates counts URLs dropped as duplicates by reason:
+	// ReasonPattern, ReasonParams, ReasonCluster or ReasonDuplicate.
+	Duplicates map[string]int64 `json:"duplicates"`
+	// Hosts breaks the counters down by host.
+	Hosts map[string]HostStats `json:"hosts"`
+	// UniqueParams is the number of distinct parameter names in URLs that passed the filters.
+	UniqueParams int `json:"unique_params"`
+	// UniquePatterns is the number of collapsed path patterns (see Patterns).
+	UniquePatterns int `json:"unique_patterns"`
+}
+
+// HostStats holds the counters of a single host.
+type HostStats struct {
+	Input      int64 `json:"input"`
+	Kept       int64 `json:"kept"`
+	Filtered   int64 `json:"filtered"`
+	Duplicates int64 `json:"duplicates"`
+}
+
+func newStats() Stats {
+	return Stats{
+		Filtered:   make(map[string]int64),
+		Duplicates: make(map[string]int64),
+		Hosts:      make(map[string]HostStats),
+	}
+}
+
+// Stats returns a snapshot of the processing counters.
+func (p *Processor) Stats() Stats {
+	p.lockAll()
+	s := p.totalStats()
+	hosts := make(map[string]HostStats, len(s.Hosts))
+	for key, h := range s.Hosts {
+		host := p.displayHost(key)
+		total := hosts[host]
+		total.Input += h.Input
+		total.Kept += h.Kept
+		total.Filtered += h.Filtered
+		total.Duplicates += h.Duplicates
+		hosts[host] = total
+	}
+	p.unlockAll()
+
+	s.Hosts = hosts
+	s.UniqueParams = p.paramNames.len()
+	s.UniquePatterns = p.patternsSeen.len()
+	return s
+}
+
+// totalStats sums the counters of all shards, with hosts by bucket key.
+// The caller must hold all shard locks.
+func (p *Processor) totalStats() Stats {
+	total := newStats()
+	for _, sh := range p.shards {
+		s := &sh.stats
+		total.Input += s.Input
+		total.Empty += s.Empty
+		total.Invalid += s.Invalid
+		total.Kept += s.Kept
+		for name, n := range s.Filtered {
+			total.Filtered[name] += n
+		}
+		for reason, n := range s.Duplicates {
+			total.Duplicates[reason] += n
+		}
+		maps.Copy(total.Hosts, s.Hosts)
+	}
+	return total
+}
+
+// record updates the counters of the host shard with a decision.
+// Lines without a host are counted in the first shard.
+func (p *Processor) record(d *Decision) {
+	sh := p.shards[0]
+	if d.host != "" {
+		sh = p.shardFor(d.host)
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	p.collapse(d, 1)
+
+	s := &sh.stats
+	s.Input++
+	var h HostStats
+	if d.host != "" {
+		h = s.Hosts[d.host]
+		h.Input++
+	}
+
+	switch {
+	case d.Kept:
+		s.Kept++
+		h.Kept++
+	case d.Reason == ReasonEmpty:
+		s.Empty++
+	case d.Reason == ReasonInvalid:
+		s.Invalid++
+	case d.Reason == ReasonFilter:
+		s.Filtered[d.Filter]++
+		h.Filtered++
+	default:
+		s.Duplicates[d.Reason]++
+		h.Duplicates++
+	}
+
+	if d.host != "" {
+		s.Hosts[d.host] = h
+	}
+}
@@ -0,0 +1,133 @@
+package uro
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Template is a kept endpoint in template form (see OutputTemplate).
+type Template struct {
+	// Template is the URL with variable path segments replaced by placeholders
+	// and parameter values removed, e.g. https://example.com/users/{int}?sort=&page=
+	Template string `json:"template"`
+	// Count is the number of input URLs collapsed into the template: its kept
+	// URLs and the duplicates they caused to be dropped. Counts are tracked
+	// only with OutputTemplate and are zero otherwise.
+	Count int64 `json:"count"`
+	// URL is the input text of the first kept URL of the template.
+	URL string `json:"url"`
+}
+
+// Templates returns the templates of the deduplicated URLs in the order of
+// Results, each template once. Duplicates dropped by a custom Deduper or in
+// low-memory mode are not attributed to a template. In streaming mode, this
+// returns nil.
+func (p *Processor) Templates() []Template {
+	if p.streaming {
+		return nil
+	}
+
+	p.lockAll()
+	results := p.collectResults()
+	// A path kept without parameters is not output once it has URLs with
+	// them, so its count goes to the first of those
+	moved := make(map[string]int64)
+	for _, sh := range p.shards {
+		for _, paths := range sh.urlMap {
+			for _, pe := range paths {
+				if len(pe.entries) > 0 && pe.raw != pe.entries[0].raw {
+					moved[pe.entries[0].raw] += p.collapsedCount(pe.raw)
+				}
+			}
+		}
+	}
+	p.unlockAll()
+
+	var templates []Template
+	index := make(map[string]int)
+	for _, r := range results {
+		t := p.template(r)
+		i, ok := index[t]
+		if !ok {
+			i = len(templates)
+			index[t] = i
+			templates = append(templates, Template{Template: t, URL: r.raw})
+		}
+		templates[i].Count += p.collapsedCount(r.raw) + moved[r.raw]
+	}
+	return templates
+}
+
+// template returns the template form of a result: the host, the path with
+// variable segments replaced by placeholders and the distinct names of the
+// parameters, except ignored ones, with empty values. A hash route follows
+// the server query with its own query.
+func (p *Processor) template(r result) string {
+	path, route := r.path, ""
+	if p.opts.HashRoutes {
+		path, route = splitRoute(r.path)
+	}
+	server, routeParams := r.params.splitRoute(r.routeParams)
+
+	var b strings.Builder
+	b.WriteString(r.host)
+	b.WriteString(p.pathTemplate(path))
+	p.writeTemplateQuery(&b, server)
+	if route != "" {
+		b.WriteString(p.pathTemplate(route))
+		p.writeTemplateQuery(&b, routeParams)
+	}
+	return b.String()
+}
+
+// writeTemplateQuery writes "?name=&name=" for the distinct parameter names.
+func (p *Processor) writeTemplateQuery(b *strings.Builder, params Params) {
+	seen := make(map[string]struct{}, len(params))
+	for _, param := range params {
+		name := param.Key
+		if p.isIgnoredParam(name) {
+			continue
+		}
+		if p.opts.GroupBrackets {
+			name = GroupBracketKey(name)
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		if len(seen) == 1 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+	}
+}
+
+// collapse counts n input URLs for the kept URL a decision was attributed
+// to: the URL itself if it was kept, or the URL it duplicates.
+func (p *Processor) collapse(d *Decision, n int64) {
+	if p.output != OutputTemplate || n <= 0 {
+		return
+	}
+	target := d.DuplicateOf
+	if d.Kept {
+		target = d.raw
+	}
+	if target == "" {
+		return
+	}
+	count, ok := p.collapsed.load(target)
+	if !ok {
+		count, _ = p.collapsed.loadOrStore(target, new(atomic.Int64))
+	}
+	count.Add(n)
+}
+
+func (p *Processor) collapsedCount(raw string) int64 {
+	if count, ok := p.collapsed.load(raw); ok {
+		return count.Load()
+	}
+	return 0
+}
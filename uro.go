@@ -44,19 +44,39 @@ package uro
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"hash/maphash"
 	"io"
+	"net"
 	"net/url"
 	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 // Version is the current version of uro
 const Version = "1.1.0"
 
+// DefaultMaxLineLength is the longest input line accepted by ProcessReader
+// when Options.MaxLineLength is not set.
+const DefaultMaxLineLength = 1024 * 1024
+
+// Scopes for tracking parameter novelty and path patterns
+// (see Options.ParamScope and Options.PatternScope).
+const (
+	ScopeGlobal = "global"
+	ScopeHost   = "host"
+	ScopePath   = "path"
+	ScopeDomain = "domain"
+)
+
 // Options configures the URL processor behavior
 type Options struct {
 	// Whitelist contains extensions to keep (e.g., []string{"php", "html"}).
@@ -77,6 +97,7 @@ type Options struct {
 	//   - "keepcontent": keep human-written content (blogs)
 	//   - "keepslash": keep trailing slash in URLs
 	//   - "vuln": only URLs with potentially vulnerable parameters
+	//   - "hpp": only URLs with repeated parameters (HTTP parameter pollution candidates)
 	Filters []string
 
 	// KeepSlash preserves trailing slashes in URLs.
@@ -85,34 +106,220 @@ type Options struct {
 
 	// Workers sets the number of parallel workers for processing.
 	// If 0 or 1, processing is sequential.
-	// Use -1 for runtime.NumCPU().
+	// Use -1 for runtime.NumCPU(). ProcessReader commits the URLs of each
+	// host (of each pattern group with PatternScope or HostGroup) in input
+	// order, so it keeps the same URLs as a sequential run unless patterns
+	// are global or the removecontent filter is on: both share state
+	// across hosts.
 	Workers int
 
+	// Ordered makes parallel processing deterministic with any options:
+	// workers only parse, canonicalize and filter URLs, and deduplication
+	// decisions are made one at a time in input order. Results, streamed
+	// output and Stats are identical to a sequential run. It has no effect
+	// without Workers > 1.
+	Ordered bool
+
+	// MaxLineLength is the longest input line (in bytes) accepted by
+	// ProcessReader. Longer lines stop reading with bufio.ErrTooLong.
+	// Defaults to DefaultMaxLineLength.
+	MaxLineLength int
+
+	// ValueAware makes parameter deduplication consider value types.
+	// Each value is classified as empty, int, float, bool, url, path, email,
+	// json, base64 or text, and the (name, class) pair is used as the novelty
+	// signal instead of the name alone. For example, ?next=/home and
+	// ?next=https://evil.tld are both kept.
+	ValueAware bool
+
+	// ParamScope controls where parameter novelty is tracked:
+	//   - "global" (default): a parameter seen on any host is no longer new
+	//   - "host": parameters are tracked separately for each host
+	//   - "path": parameters are tracked separately for each host and path
+	//     (paths collapsing into one pattern count as one path)
+	// With "host" or "path" scope, a URL whose path collapses into an already
+	// seen pattern is still kept if it brings parameters new in that scope.
+	ParamScope string
+
+	// PatternScope controls where path patterns (e.g. /users/\d+) are deduplicated:
+	//   - "global" (default): a pattern seen on any host drops matching paths everywhere
+	//   - "host": patterns are tracked separately for each host
+	//   - "domain": hosts sharing a registrable domain (by the Public Suffix List) share patterns
+	//     (a.example.com and b.example.com, but not example.org)
+	PatternScope string
+
+	// HostGroup maps a host (scheme://host) to the key that scopes pattern
+	// deduplication. Hosts with the same key share patterns.
+	// If set, PatternScope is ignored.
+	HostGroup func(host string) string
+
+	// Dedup selects the deduplication strategy:
+	//   - "classic" (default): the original uro logic (new paths, patterns, new params)
+	//   - "exact": one URL per exact host, path and parameter set
+	//   - "keys": one URL per host, path and set of parameter names
+	//   - "template": one URL per host and path template (/users/{int})
+	Dedup string
+
+	// Deduper sets a custom deduplication strategy. If set, Dedup is ignored.
+	Deduper Deduper
+
+	// Cluster enables fuzzy clustering of new paths. Paths are split into
+	// tokens and compared with kept paths of the same host and depth; a path
+	// similar enough to one of them (e.g. /product/red-shoe-size-9 and
+	// /product/blue-shoe-size-10) is dropped. Numbers, detected identifiers,
+	// high-entropy tokens and a leading locale segment match each other.
+	Cluster bool
+
+	// ClusterThreshold is the minimum share of matching tokens (0..1) for a
+	// path to join a cluster. Defaults to DefaultClusterThreshold.
+	ClusterThreshold float64
+
+	// Order sets the order of Results and WriteResults:
+	//   - "input" (default): the order in which URLs were kept
+	//   - "lex": lexicographic by host, path and query
+	//   - "host": grouped by host (hosts in order of appearance), then input order
+	// Parameters are always output in their source order.
+	Order string
+
+	// Output sets the form in which kept URLs are returned and streamed:
+	//   - "canonical": rebuilt as scheme://host/path?query (fragments and
+	//     userinfo are dropped, repeated parameters are kept in source
+	//     order); the default without StreamOutput
+	//   - "original": the input text of the kept URL as is; the default
+	//     with StreamOutput, so streamed URLs stay as they were given
+	//   - "template": the endpoint template, with variable path segments
+	//     replaced by placeholders and parameter values removed
+	//     (https://example.com/users/{int}?sort=&page=, see Templates)
+	// The same form is used by Results, WriteResults and StreamOutput.
+	Output string
+
+	// HashRoutes treats "#/" and "#!/" fragments of single-page apps as part
+	// of the path: /#/admin/users?id=1 has the path "/#/admin/users" and the
+	// parameter id, which take part in pattern collapse, parameter novelty
+	// and filters like real ones. Canonical output keeps the server query
+	// and the route query apart: /app?x=1#/route?y=2.
+	HashRoutes bool
+
+	// GroupBrackets groups bracket-notation parameters for novelty checks:
+	// user[name] and user[role] both count as user[*], ids[] as ids[*].
+	GroupBrackets bool
+
+	// KeepRepeated makes a repeated parameter (a=1&a=2) a novelty key of its
+	// own, so HTTP parameter pollution candidates are not dropped as
+	// duplicates of the same URL without repetition.
+	KeepRepeated bool
+
+	// Canonicalize enables RFC 3986 canonicalization of URLs before
+	// deduplication. Available steps: "scheme", "host", "port", "dots",
+	// "percent", "unreserved", "nfc", or "all" (see Canonicalize).
+	// The original text is still available via Output: "original".
+	Canonicalize []string
+
+	// HostRules makes equivalent hosts share one deduplication bucket
+	// (scheme, www, ports, IDN, aliases). See HostRules.
+	HostRules HostRules
+
+	// MatrixParams parses path segment parameters (/item;id=5;color=red)
+	// into the parameter list and removes them from the path. In canonical
+	// output they follow the path as query parameters.
+	MatrixParams bool
+
+	// StripSessions removes session tokens from paths and queries:
+	// parameters named in SessionTokens (;jsessionid=..., ?PHPSESSID=...)
+	// and ASP.NET cookieless segments (/(S(lit3py55t21z5v55vlm25s55))/).
+	StripSessions bool
+
+	// SessionTokens replaces DefaultSessionTokens, the parameter names
+	// removed by StripSessions. Names are case-insensitive glob patterns.
+	SessionTokens []string
+
+	// IgnoreTracking ignores tracking and cache-buster parameters listed in
+	// DefaultIgnoreParams (utm_*, fbclid, gclid, _, v, cb, ts, ...).
+	// Ignored parameters don't make a URL new.
+	IgnoreTracking bool
+
+	// IgnoreParams lists additional ignored parameters (glob patterns, e.g. "utm_*").
+	IgnoreParams []string
+
+	// StripIgnored removes ignored parameters from URLs instead of only
+	// excluding them from novelty checks.
+	StripIgnored bool
+
+	// KeepParams keeps only parameters matching these glob patterns and
+	// removes all others.
+	KeepParams []string
+
+	// Normalizers enables built-in detectors for variable path segments.
+	// Paths that differ only in detected segments are treated as one pattern,
+	// like purely numeric segments. Available detectors:
+	//   - "uuid": UUIDs (3f2a9c1e-7b4d-4c1a-9e2f-1a2b3c4d5e6f)
+	//   - "hex": hex hashes and identifiers of 16+ characters
+	//   - "ulid": ULIDs
+	//   - "base64": base62/base64 tokens mixing letter case and digits
+	//   - "date": dates (2024-05-01, 2024_05_01, 2024.05.01)
+	//   - "email": email addresses
+	//   - "all": all of the above
+	Normalizers []string
+
+	// SegmentNormalizers adds custom path segment detectors.
+	// They are tried after the built-in detectors enabled via Normalizers.
+	SegmentNormalizers []SegmentNormalizer
+
+	// LowMemory stores seen paths, parameters and patterns in fixed-size
+	// Bloom filters instead of maps, at the price of dropping a new URL with
+	// a probability of about FalsePositiveRate per check. Works only with
+	// StreamOutput and is ignored otherwise (see README, Low-Memory Mode).
+	LowMemory bool
+
+	// ExpectedURLs is the number of distinct keys each LowMemory filter is
+	// sized for. Defaults to DefaultExpectedURLs.
+	ExpectedURLs int
+
+	// FalsePositiveRate is the target false drop rate of LowMemory filters.
+	// Defaults to DefaultFalsePositiveRate (0.1%).
+	FalsePositiveRate float64
+
 	// StreamOutput is called immediately when a URL passes all filters.
 	// If set, URLs are output in streaming mode instead of being stored.
 	// This is useful for processing large files with minimal memory.
-	// Note: The callback must be thread-safe if Workers > 1.
+	// Note: The callback must be thread-safe if Workers > 1. It is called
+	// without processor locks held and may call Count or Stats.
 	StreamOutput func(url string)
 }
 
 // Processor handles URL deduplication
 type Processor struct {
-	opts            *Options
-	urlMap          map[string]map[string][]map[string]string
-	paramsSeen      map[string]struct{}
-	patternsSeen    map[string]struct{}
-	contentPrefixes []string
-	extList         []string
-	filters         []string
-	strict          bool
-	keepSlash       bool
-	streaming       bool
-	workers         int
-	streamOutput    func(string)
-	reInt           *regexp.Regexp
-	reContent       *regexp.Regexp
-	mu              sync.Mutex
-	count           int64
+	opts             *Options
+	shards           [numShards]*shard
+	seed             maphash.Seed
+	paramsSeen       concurrentMap[struct{}]
+	patternsSeen     concurrentMap[Pattern]
+	paramNames       concurrentMap[struct{}]
+	collapsed        concurrentMap[*atomic.Int64] // kept URL -> input URLs collapsed into it
+	contentPrefixes  prefixList
+	dedupMu          sync.Mutex // serializes custom Deduper calls
+	extList          []string
+	filters          []string
+	stateful         int // index of the first filter that depends on the processor state
+	strict           bool
+	paramScope       string
+	hostGroup        func(string) string
+	deduper          Deduper
+	clusterThreshold float64
+	keepSlash        bool
+	streaming        bool
+	workers          int
+	streamOutput     func(string)
+	normalizers      []SegmentNormalizer
+	reContent        *regexp.Regexp
+	maxLineLength    int
+	lowMemory        bool
+	seq              atomic.Int64
+	streamed         atomic.Int64 // URLs streamed, for Count
+	baseSeq          int64        // last seq of a loaded state
+	order            string
+	output           string
+	canonical        map[string]bool
 }
 
 // NewProcessor creates a new URL processor with the given options.
@@ -129,17 +336,32 @@ func NewProcessor(opts *Options) *Processor {
 
 	p := &Processor{
 		opts:         opts,
-		urlMap:       make(map[string]map[string][]map[string]string),
-		paramsSeen:   make(map[string]struct{}),
-		patternsSeen: make(map[string]struct{}),
-		reInt:        regexp.MustCompile(`/\d+([?/]|$)`),
+		seed:         maphash.MakeSeed(),
 		reContent:    regexp.MustCompile(`(post|blog)s?|docs|support/|/(\d{4}|pages?)/\d+/`),
 		streaming:    opts.StreamOutput != nil,
+		lowMemory:    opts.LowMemory && opts.StreamOutput != nil,
 		streamOutput: opts.StreamOutput,
 		workers:      workers,
 	}
 
+	for i := range p.shards {
+		p.shards[i] = newShard()
+	}
+	p.maxLineLength = opts.MaxLineLength
+	if p.maxLineLength <= 0 {
+		p.maxLineLength = DefaultMaxLineLength
+	}
+	p.canonical = canonStepSet(opts.Canonicalize)
+	p.clusterThreshold = opts.ClusterThreshold
+	if p.clusterThreshold <= 0 {
+		p.clusterThreshold = DefaultClusterThreshold
+	}
+
 	p.setupFilters()
+	p.setupNormalizers()
+	p.setupScopes()
+	p.setupDeduper()
+	p.setupOrder()
 	return p
 }
 
@@ -147,6 +369,42 @@ func NewProcessor(opts *Options) *Processor {
 // Returns true if the URL was kept, false if it was filtered out.
 // This method is thread-safe.
 func (p *Processor) Process(rawURL string) bool {
+	return p.process(rawURL).Kept
+}
+
+// process handles a URL and records the decision in the statistics.
+func (p *Processor) process(rawURL string) Decision {
+	d := p.decide(rawURL)
+	p.record(&d)
+	return d
+}
+
+func (p *Processor) decide(rawURL string) Decision {
+	d, pu := p.prepare(rawURL)
+	if pu != nil {
+		p.commit(pu, &d)
+	}
+	return d
+}
+
+// preparedURL is a URL that passed the stateless steps of decide.
+type preparedURL struct {
+	u           *url.URL
+	raw         string
+	host        string
+	path        string
+	params      Params
+	routeParams int
+	seq         int64 // number assigned before commit in parallel mode, or 0
+}
+
+// prepare runs the steps of decide that don't depend on the processor state:
+// normalization, canonicalization, parsing and the filters before the first
+// stateful one. It returns a nil URL if the decision is already final.
+// prepare may run concurrently with commit.
+func (p *Processor) prepare(rawURL string) (Decision, *preparedURL) {
+	d := Decision{URL: rawURL}
+
 	// Normalize
 	rawURL = strings.ToValidUTF8(rawURL, "")
 	rawURL = strings.TrimSpace(rawURL)
@@ -155,44 +413,154 @@ func (p *Processor) Process(rawURL string) bool {
 	}
 
 	if rawURL == "" {
-		return false
+		d.Reason = ReasonEmpty
+		return d, nil
+	}
+
+	// Canonicalize
+	target := rawURL
+	if len(p.canonical) > 0 {
+		canonical, err := canonicalize(rawURL, p.canonical)
+		if err != nil {
+			d.Reason = ReasonInvalid
+			return d, nil
+		}
+		target = canonical
+		if !p.keepSlash {
+			target = strings.TrimSuffix(target, "/")
+		}
 	}
 
 	// Parse URL
-	u, err := url.Parse(rawURL)
+	u, err := url.Parse(target)
 	if err != nil || u.Host == "" {
-		return false
+		d.Reason = ReasonInvalid
+		return d, nil
 	}
 
-	return p.processURL(u, rawURL)
+	return d, p.prepareURL(u, rawURL, &d)
 }
 
 // ProcessReader reads URLs from an io.Reader (one per line) and processes them.
 // Returns the number of URLs that were kept.
 // If Workers > 1, processing is done in parallel.
+// Read errors are ignored; use ProcessReaderContext to get them.
 func (p *Processor) ProcessReader(r io.Reader) int {
-	if p.workers > 1 {
-		return p.processReaderParallel(r)
+	count, _ := p.ProcessReaderContext(context.Background(), r)
+	return count
+}
+
+// ProcessReaderContext is like ProcessReader, but stops when ctx is done
+// and returns the first read error (e.g. bufio.ErrTooLong for lines longer
+// than Options.MaxLineLength) or ctx.Err(). URLs processed before the error
+// are kept. If Workers > 1, workers are stopped before it returns.
+func (p *Processor) ProcessReaderContext(ctx context.Context, r io.Reader) (int, error) {
+	switch {
+	case p.workers > 1 && p.opts.Ordered:
+		return p.processReaderOrdered(ctx, r)
+	case p.workers > 1:
+		return p.processReaderParallel(ctx, r)
 	}
-	return p.processReaderSequential(r)
+	return p.processReaderSequential(ctx, r)
 }
 
-func (p *Processor) processReaderSequential(r io.Reader) int {
+// newScanner returns a line scanner limited to the maximum line length.
+func (p *Processor) newScanner(r io.Reader) *bufio.Scanner {
 	scanner := bufio.NewScanner(r)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	buf := make([]byte, 0, min(64*1024, p.maxLineLength))
+	scanner.Buffer(buf, p.maxLineLength)
+	return scanner
+}
+
+func (p *Processor) processReaderSequential(ctx context.Context, r io.Reader) (int, error) {
+	scanner := p.newScanner(r)
 
 	count := 0
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
 		if p.Process(scanner.Text()) {
 			count++
 		}
 	}
-	return count
+	return count, scanner.Err()
+}
+
+// processReaderParallel prepares lines on the workers and commits them on
+// as many committers, chosen by commitKey: URLs of one host (or pattern
+// group) are committed in input order, URLs of different ones concurrently.
+func (p *Processor) processReaderParallel(ctx context.Context, r io.Reader) (int, error) {
+	type commitJob struct {
+		d  Decision
+		pu *preparedURL
+	}
+	queues := make([]chan commitJob, p.workers)
+	var wg sync.WaitGroup
+	var count atomic.Int64
+
+	// Start committers
+	for i := range queues {
+		queues[i] = make(chan commitJob, 100)
+		wg.Add(1)
+		go func(jobs <-chan commitJob) {
+			defer wg.Done()
+			for j := range jobs {
+				p.commit(j.pu, &j.d)
+				p.record(&j.d)
+				if j.d.Kept {
+					count.Add(1)
+				}
+			}
+		}(queues[i])
+	}
+
+	err := p.prepareLines(ctx, r, func(d Decision, pu *preparedURL) {
+		if pu == nil {
+			p.record(&d)
+			return
+		}
+		// Kept URLs are numbered in input order, so results are ordered
+		// as in a sequential run
+		pu.seq = p.seq.Add(1)
+		queues[maphash.String(p.seed, p.commitKey(pu.host))%uint64(len(queues))] <- commitJob{d, pu}
+	})
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	return int(count.Load()), err
+}
+
+// processReaderOrdered prepares lines in parallel and commits them one at
+// a time in input order, so the result is the same as processReaderSequential.
+func (p *Processor) processReaderOrdered(ctx context.Context, r io.Reader) (int, error) {
+	count := 0
+	err := p.prepareLines(ctx, r, func(d Decision, pu *preparedURL) {
+		if pu != nil {
+			p.commit(pu, &d)
+		}
+		p.record(&d)
+		if d.Kept {
+			count++
+		}
+	})
+	return count, err
 }
 
-func (p *Processor) processReaderParallel(r io.Reader) int {
-	lines := make(chan string, p.workers*100)
+// prepareLines reads lines, prepares them on the workers and calls fn with
+// the results in input order. It returns the first read error or ctx.Err().
+func (p *Processor) prepareLines(ctx context.Context, r io.Reader, fn func(d Decision, pu *preparedURL)) error {
+	type prepared struct {
+		d  Decision
+		pu *preparedURL
+	}
+	type job struct {
+		line string
+		done chan prepared
+	}
+	jobs := make(chan job, p.workers*100)
+	pending := make(chan job, p.workers*100) // jobs in input order
 	var wg sync.WaitGroup
 
 	// Start workers
@@ -200,113 +568,171 @@ func (p *Processor) processReaderParallel(r io.Reader) int {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for line := range lines {
-				if p.Process(line) {
-					atomic.AddInt64(&p.count, 1)
-				}
+			for j := range jobs {
+				d, pu := p.prepare(j.line)
+				j.done <- prepared{d, pu}
 			}
 		}()
 	}
 
-	// Read and distribute lines
-	scanner := bufio.NewScanner(r)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	// Read lines and hand them to workers and, in order, to fn.
+	// A job is queued for workers right after it is queued as pending, so
+	// the loop below never waits for a job that no worker can get.
+	scanner := p.newScanner(r)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for scanner.Scan() {
+			j := job{line: scanner.Text(), done: make(chan prepared, 1)}
+			select {
+			case pending <- j:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+			jobs <- j
+		}
+		errc <- scanner.Err()
+	}()
 
-	for scanner.Scan() {
-		lines <- scanner.Text()
+	for j := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		res := <-j.done
+		fn(res.d, res.pu)
+	}
+	for range pending {
 	}
-	close(lines)
-
 	wg.Wait()
-	return int(atomic.LoadInt64(&p.count))
+
+	err := <-errc
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
 }
 
 // Results returns all deduplicated URLs as a slice.
 // In streaming mode, this returns an empty slice.
+// Use Entries to iterate over large result sets.
+// With OutputTemplate, each template is returned once.
 func (p *Processor) Results() []string {
-	if p.streaming {
-		return nil
+	if p.output == OutputTemplate {
+		var results []string
+		for _, t := range p.Templates() {
+			results = append(results, t.Template)
+		}
+		return results
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	var results []string
-	for host, paths := range p.urlMap {
-		for path, paramsList := range paths {
-			if len(paramsList) > 0 {
-				for _, params := range paramsList {
-					results = append(results, host+path+mapToQuery(params))
-				}
-			} else {
-				results = append(results, host+path)
-			}
-		}
+	for e := range p.Entries() {
+		results = append(results, e.URL)
 	}
 	return results
 }
 
 // WriteResults writes all deduplicated URLs to an io.Writer.
 // In streaming mode, this is a no-op since URLs were already output.
+// With OutputTemplate, each template is written once, followed by a tab
+// and the number of input URLs collapsed into it.
 func (p *Processor) WriteResults(w io.Writer) error {
-	if p.streaming {
+	if p.output == OutputTemplate {
+		for _, t := range p.Templates() {
+			if _, err := fmt.Fprintf(w, "%s\t%d\n", t.Template, t.Count); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	for host, paths := range p.urlMap {
-		for path, paramsList := range paths {
-			if len(paramsList) > 0 {
-				for _, params := range paramsList {
-					if _, err := fmt.Fprintln(w, host+path+mapToQuery(params)); err != nil {
-						return err
-					}
-				}
-			} else {
-				if _, err := fmt.Fprintln(w, host+path); err != nil {
-					return err
-				}
-			}
+	for e := range p.Entries() {
+		if _, err := fmt.Fprintln(w, e.URL); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Count returns the number of unique URLs currently stored.
+// Count returns the number of unique URLs currently stored (the length of
+// Results). In streaming mode it is the number of URLs streamed so far.
 func (p *Processor) Count() int {
 	if p.streaming {
-		return int(atomic.LoadInt64(&p.count))
+		return int(p.streamed.Load())
 	}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	count := 0
-	for _, paths := range p.urlMap {
-		for _, paramsList := range paths {
-			if len(paramsList) > 0 {
-				count += len(paramsList)
-			} else {
-				count++
+	for _, s := range p.shards {
+		s.mu.Lock()
+		for _, paths := range s.urlMap {
+			for _, pe := range paths {
+				count += max(len(pe.entries), 1)
 			}
 		}
+		s.mu.Unlock()
 	}
 	return count
 }
 
+// Pattern describes a collapsed path pattern and the host that owns it.
+type Pattern struct {
+	// Pattern is the path template, e.g. /api/users/\d+
+	Pattern string
+	// Group is the host grouping key the pattern is scoped to.
+	// It is empty when patterns are global.
+	Group string
+	// Host is the host (scheme://host) that first produced the pattern.
+	Host string
+	// URL is the input URL that first produced the pattern.
+	URL string
+}
+
+// Patterns returns all collapsed path patterns seen so far,
+// sorted by group and pattern.
+func (p *Processor) Patterns() []Pattern {
+	var patterns []Pattern
+	p.patternsSeen.each(func(_ string, pattern Pattern) {
+		patterns = append(patterns, pattern)
+	})
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Group != patterns[j].Group {
+			return patterns[i].Group < patterns[j].Group
+		}
+		return patterns[i].Pattern < patterns[j].Pattern
+	})
+	return patterns
+}
+
 // Reset clears all processed URLs and resets the processor state.
 func (p *Processor) Reset() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	p.lockAll()
+	defer p.unlockAll()
+	p.reset()
+}
 
-	p.urlMap = make(map[string]map[string][]map[string]string)
-	p.paramsSeen = make(map[string]struct{})
-	p.patternsSeen = make(map[string]struct{})
-	p.contentPrefixes = nil
-	atomic.StoreInt64(&p.count, 0)
+// reset clears the processor state. The caller must hold all shard locks.
+func (p *Processor) reset() {
+	for i := range p.shards {
+		s := p.shards[i]
+		s.urlMap = make(map[string]map[string]*pathEntry)
+		s.clusters = make(map[string][]*pathCluster)
+		s.hostReps = make(map[string]string)
+		s.drops = nil
+		s.stats = newStats()
+	}
+	p.seq.Store(0)
+	p.streamed.Store(0)
+	p.baseSeq = 0
+	p.paramsSeen.clear()
+	p.patternsSeen.clear()
+	p.paramNames.clear()
+	p.collapsed.clear()
+	p.contentPrefixes.reset()
+	p.dedupMu.Lock()
+	p.deduper.Reset()
+	p.dedupMu.Unlock()
 }
 
 // --- Internal methods ---
@@ -370,6 +796,13 @@ func (p *Processor) setupFilters() {
 
 	p.filters = activeFilters
 
+	// removecontent remembers content prefixes, so it and the filters
+	// after it run when the decision is committed
+	p.stateful = len(p.filters)
+	if i := slices.Index(p.filters, "removecontent"); i >= 0 {
+		p.stateful = i
+	}
+
 	// Set strict mode
 	for _, f := range filters {
 		if f == "hasext" || f == "noext" {
@@ -379,113 +812,194 @@ func (p *Processor) setupFilters() {
 	}
 }
 
-func (p *Processor) processURL(u *url.URL, rawURL string) bool {
-	host := u.Scheme + "://" + u.Host
-	path := u.Path
-	params := paramsToMap(u.RawQuery)
+func (p *Processor) setupOrder() {
+	switch order := strings.ToLower(strings.TrimSpace(p.opts.Order)); order {
+	case OrderLex, OrderHost:
+		p.order = order
+	default:
+		p.order = OrderInput
+	}
 
-	// Apply filters first (no lock needed for read-only filters)
-	if !p.applyFilters(path, params) {
-		return false
+	switch output := strings.ToLower(strings.TrimSpace(p.opts.Output)); {
+	case output == OutputCanonical || output == OutputOriginal || output == OutputTemplate:
+		p.output = output
+	case p.streaming:
+		p.output = OutputOriginal
+	default:
+		p.output = OutputCanonical
 	}
+}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+func (p *Processor) setupScopes() {
+	switch scope := strings.ToLower(strings.TrimSpace(p.opts.ParamScope)); scope {
+	case ScopeHost, ScopePath:
+		p.paramScope = scope
+	default:
+		p.paramScope = ScopeGlobal
+	}
+
+	switch {
+	case p.opts.HostGroup != nil:
+		p.hostGroup = p.opts.HostGroup
+	case strings.EqualFold(strings.TrimSpace(p.opts.PatternScope), ScopeHost):
+		p.hostGroup = func(host string) string { return host }
+	case strings.EqualFold(strings.TrimSpace(p.opts.PatternScope), ScopeDomain):
+		p.hostGroup = registrableDomain
+	default:
+		p.hostGroup = func(string) string { return "" }
+	}
+}
 
-	// Find new params
-	newParams := []string{}
-	for param := range params {
-		if _, seen := p.paramsSeen[param]; !seen {
-			newParams = append(newParams, param)
+// paramScopeKey returns the prefix for paramsSeen keys according to ParamScope.
+// In path scope, paths collapsing into one pattern share the same scope.
+func (p *Processor) paramScopeKey(host, path string) string {
+	switch p.paramScope {
+	case ScopeHost:
+		return host + "\x00"
+	case ScopePath:
+		if pattern, ok := p.createPattern(path); ok {
+			path = pattern
 		}
+		return host + path + "\x00"
+	default:
+		return ""
 	}
+}
 
-	// Update seen params
-	for _, param := range newParams {
-		p.paramsSeen[param] = struct{}{}
+// prepareURL extracts the host, path and parameters of a parsed URL and
+// applies the stateless filters. It returns nil if the URL is filtered out.
+func (p *Processor) prepareURL(u *url.URL, rawURL string, d *Decision) *preparedURL {
+	host := p.hostKey(u)
+	d.host = host
+	path, routeQuery := u.Path, ""
+	if p.opts.HashRoutes {
+		path, routeQuery = hashRoute(path, u.EscapedFragment())
 	}
 
-	// Initialize host map if needed
-	if _, ok := p.urlMap[host]; !ok {
-		p.urlMap[host] = make(map[string][]map[string]string)
+	// Matrix parameters and session tokens
+	var matrix Params
+	if p.opts.MatrixParams || p.opts.StripSessions {
+		path, matrix = p.splitMatrix(path)
 	}
+	params := append(matrix, ParseParams(u.RawQuery)...)
+	route := ParseParams(routeQuery)
+	if p.opts.StripSessions {
+		params, route = p.stripSessions(params), p.stripSessions(route)
+	}
+	params, route = p.projectParams(params), p.projectParams(route)
 
-	// Check if path exists
-	_, pathExists := p.urlMap[host][path]
+	// Route parameters follow the server ones
+	routeParams := len(route)
+	params = append(params, route...)
 
-	if !pathExists {
-		// Check numeric pattern
-		if p.reInt.MatchString(path) {
-			pattern := p.createPattern(path)
-			if _, seen := p.patternsSeen[pattern]; seen {
-				return false
-			}
-			p.patternsSeen[pattern] = struct{}{}
-		}
+	// Apply filters first (no lock needed for read-only filters)
+	if filter, match, ok := p.applyFilters(p.filters[:p.stateful], path, params); !ok {
+		d.Reason, d.Filter, d.Match = ReasonFilter, filter, match
+		return nil
+	}
+	return &preparedURL{u: u, raw: rawURL, host: host, path: path, params: params, routeParams: routeParams}
+}
 
-		// Add new path
-		p.urlMap[host][path] = []map[string]string{}
-		if len(params) > 0 {
-			p.urlMap[host][path] = append(p.urlMap[host][path], params)
-		}
+// commit finishes the decision of a prepared URL: it applies the stateful
+// filters and deduplicates the URL against the processor state.
+func (p *Processor) commit(pu *preparedURL, d *Decision) {
+	path, params := pu.path, pu.params
+	if filter, match, ok := p.applyFilters(p.filters[p.stateful:], path, params); !ok {
+		d.Reason, d.Filter, d.Match = ReasonFilter, filter, match
+		return
+	}
 
-		// Stream output if enabled
-		if p.streaming {
-			atomic.AddInt64(&p.count, 1)
-			p.streamOutput(rawURL)
+	if !p.lowMemory {
+		for _, param := range params {
+			p.paramNames.add(param.Key, struct{}{})
 		}
-		return true
 	}
+	p.dedupe(pu, d)
 
-	// Path exists, check params
-	if len(newParams) > 0 {
-		p.urlMap[host][path] = append(p.urlMap[host][path], params)
-		if p.streaming {
-			atomic.AddInt64(&p.count, 1)
-			p.streamOutput(rawURL)
-		}
-		return true
-	} else if len(params) > 0 && compareParams(p.urlMap[host][path], params) {
-		p.urlMap[host][path] = append(p.urlMap[host][path], params)
-		if p.streaming {
-			atomic.AddInt64(&p.count, 1)
-			p.streamOutput(rawURL)
+	// Stream output once the shard lock is released, so that the callback
+	// may call the processor (Count, Stats)
+	if d.Kept && p.streaming {
+		p.streamed.Add(1)
+		p.streamOutput(d.Output)
+	}
+}
+
+// dedupe deduplicates a filtered URL against the processor state under the
+// lock of its shard and stores it if it is kept.
+func (p *Processor) dedupe(pu *preparedURL, d *Decision) {
+	u, rawURL, host, path := pu.u, pu.raw, pu.host, pu.path
+	s := p.shardFor(host)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.opts.HostRules.enabled() {
+		p.updateHostRep(host, u.Scheme+"://"+u.Host)
+	}
+
+	// Drop new paths that fall into a known cluster
+	var tokens [][]string
+	if _, known := s.urlMap[host][path]; p.opts.Cluster && !known {
+		tokens = tokenizePath(path)
+		if cluster := p.matchCluster(host, tokens); cluster != nil {
+			d.Reason, d.DuplicateOf = ReasonCluster, cluster.raw
+			p.logDrop(s, pu, d)
+			return
 		}
-		return true
 	}
 
-	return false
+	c := &Candidate{
+		Host:        host,
+		Path:        path,
+		Params:      pu.params,
+		Template:    p.pathTemplate(path),
+		Raw:         rawURL,
+		routeParams: pu.routeParams,
+		seq:         pu.seq,
+	}
+	if !p.keep(c, d) {
+		p.logDrop(s, pu, d)
+		return
+	}
+	r := p.store(c)
+	if tokens != nil {
+		p.addCluster(host, tokens, rawURL)
+	}
+	d.Kept, d.raw = true, rawURL
+	d.Output = p.format(r)
 }
 
-func (p *Processor) applyFilters(path string, params map[string]string) bool {
-	for _, f := range p.filters {
-		if !p.applyFilter(f, path, params) {
-			return false
+// applyFilters returns the first of filters rejecting the URL and what it matched.
+func (p *Processor) applyFilters(filters []string, path string, params Params) (filter, match string, ok bool) {
+	for _, f := range filters {
+		if match, ok := p.applyFilter(f, path, params); !ok {
+			return f, match, false
 		}
 	}
-	return true
+	return "", "", true
 }
 
-func (p *Processor) applyFilter(name, path string, params map[string]string) bool {
+func (p *Processor) applyFilter(name, path string, params Params) (string, bool) {
 	switch name {
 	case "hasext":
-		return hasExtension(path)
+		return "", hasExtension(path)
 	case "noext":
-		return !hasExtension(path)
+		return getExtension(path), !hasExtension(path)
 	case "hasparams":
-		return len(params) > 0
+		return "", len(params) > 0
 	case "noparams":
-		return len(params) == 0
+		return "", len(params) == 0
 	case "whitelist":
-		return p.checkWhitelist(path)
+		return getExtension(path), p.checkWhitelist(path)
 	case "blacklist":
-		return p.checkBlacklist(path)
+		return getExtension(path), p.checkBlacklist(path)
 	case "removecontent":
 		return p.checkContent(path)
 	case "vuln":
-		return p.checkVuln(params)
+		return "", p.checkVuln(params)
+	case "hpp":
+		return "", len(params.Repeated()) > 0
 	default:
-		return true
+		return "", true
 	}
 }
 
@@ -515,97 +1029,110 @@ func (p *Processor) checkBlacklist(path string) bool {
 	return true
 }
 
-func (p *Processor) checkContent(path string) bool {
-	// Check hyphen count
+// checkContent returns false for human-written content (blog posts, docs)
+// together with the slug or content prefix that matched.
+func (p *Processor) checkContent(path string) (string, bool) {
+	// Check hyphen count (detected identifiers like UUIDs are not slugs)
 	for _, part := range strings.Split(path, "/") {
-		if strings.Count(part, "-") > 3 {
-			return false
+		if strings.Count(part, "-") > 3 && p.normalizeSegment(part) == "" {
+			return part, false
 		}
 	}
 
-	// Need lock for contentPrefixes
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	// Check cached prefixes
-	for _, prefix := range p.contentPrefixes {
+	for _, prefix := range p.contentPrefixes.load() {
 		if strings.HasPrefix(path, prefix) {
-			return false
+			return prefix, false
 		}
 	}
 
 	// Check regex
 	match := p.reContent.FindStringIndex(path)
 	if match != nil {
-		p.contentPrefixes = append(p.contentPrefixes, path[:match[1]])
+		p.contentPrefixes.add(path[:match[1]])
 	}
 
-	return true
+	return "", true
 }
 
-func (p *Processor) checkVuln(params map[string]string) bool {
-	for param := range params {
-		if _, ok := vulnParams[param]; ok {
+func (p *Processor) checkVuln(params Params) bool {
+	for _, param := range params {
+		if _, ok := vulnParams[param.Key]; ok {
 			return true
 		}
 	}
 	return false
 }
 
-func (p *Processor) createPattern(path string) string {
+// createPattern builds a template of the path up to its last variable segment.
+// Returns false if the path has no variable segments.
+func (p *Processor) createPattern(path string) (string, bool) {
 	parts := strings.Split(path, "/")
 	newParts := make([]string, 0, len(parts))
-	lastIndex := 0
+	lastIndex := -1
 
 	for i, part := range parts {
-		if isDigit(part) {
+		if token := p.normalizeSegment(part); token != "" {
 			lastIndex = i
-			newParts = append(newParts, `\d+`)
+			newParts = append(newParts, token)
 		} else {
 			newParts = append(newParts, regexp.QuoteMeta(part))
 		}
 	}
 
-	return strings.Join(newParts[:lastIndex+1], "/")
+	if lastIndex < 0 {
+		return "", false
+	}
+	return strings.Join(newParts[:lastIndex+1], "/"), true
 }
 
 // --- Helper functions ---
 
-func paramsToMap(query string) map[string]string {
-	result := make(map[string]string)
-	if query == "" {
-		return result
+// hashRoute appends a "#/" or "#!/" fragment route to the path and returns
+// the route query. Other fragments are ignored.
+func hashRoute(path, fragment string) (string, string) {
+	marker, route := "#", fragment
+	if strings.HasPrefix(route, "!") {
+		marker, route = "#!", route[1:]
+	}
+	if !strings.HasPrefix(route, "/") {
+		return path, ""
+	}
+
+	route, routeQuery, _ := strings.Cut(route, "?")
+	if decoded, err := url.PathUnescape(route); err == nil {
+		route = decoded
 	}
-	for _, pair := range strings.Split(query, "&") {
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) == 2 && parts[0] != "" {
-			result[parts[0]] = parts[1]
-		} else if len(parts) == 1 && parts[0] != "" {
-			result[parts[0]] = ""
+	return path + marker + route, routeQuery
+}
+
+// splitRoute splits a path with a hash route at the route marker.
+// The route is empty if the path has none.
+func splitRoute(path string) (string, string) {
+	for i := 0; i < len(path); i++ {
+		if rest := path[i+1:]; path[i] == '#' && (strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "!/")) {
+			return path[:i], path[i:]
 		}
 	}
-	return result
+	return path, ""
 }
 
-func mapToQuery(params map[string]string) string {
+// mapToQuery builds a query string (with the leading "?") from params.
+func mapToQuery(params Params) string {
 	if len(params) == 0 {
 		return ""
 	}
-	pairs := make([]string, 0, len(params))
-	for k, v := range params {
-		pairs = append(pairs, k+"="+v)
-	}
-	return "?" + strings.Join(pairs, "&")
+	return "?" + params.Encode()
 }
 
-func compareParams(existing []map[string]string, new map[string]string) bool {
+func (p *Processor) compareParams(existing []*urlEntry, new Params) bool {
 	seen := make(map[string]struct{})
-	for _, params := range existing {
-		for key := range params {
+	for _, e := range existing {
+		for _, key := range p.paramKeys(e.params) {
 			seen[key] = struct{}{}
 		}
 	}
-	for key := range new {
+	for _, key := range p.paramKeys(new) {
 		if _, ok := seen[key]; !ok {
 			return true
 		}
@@ -613,11 +1140,20 @@ func compareParams(existing []map[string]string, new map[string]string) bool {
 	return false
 }
 
+// cleanArgs splits comma-separated arguments, lowercases them and removes
+// duplicates, keeping the order of first occurrence.
 func cleanArgs(args []string) []string {
 	if len(args) == 0 {
 		return nil
 	}
-	result := make(map[string]struct{})
+	seen := make(map[string]struct{})
+	var output []string
+	add := func(arg string) {
+		if _, ok := seen[arg]; !ok {
+			seen[arg] = struct{}{}
+			output = append(output, arg)
+		}
+	}
 	for _, arg := range args {
 		arg = strings.TrimSpace(arg)
 		if arg == "" {
@@ -627,17 +1163,13 @@ func cleanArgs(args []string) []string {
 			for _, part := range strings.Split(arg, ",") {
 				part = strings.TrimSpace(strings.ToLower(part))
 				if part != "" {
-					result[part] = struct{}{}
+					add(part)
 				}
 			}
 		} else {
-			result[strings.ToLower(arg)] = struct{}{}
+			add(strings.ToLower(arg))
 		}
 	}
-	output := make([]string, 0, len(result))
-	for k := range result {
-		output = append(output, k)
-	}
 	return output
 }
 
@@ -663,6 +1195,29 @@ func getExtension(path string) string {
 	return strings.ToLower(lastPart[lastDot+1:])
 }
 
+// registrableDomain returns the registrable domain of a host (scheme://host:port)
+// by the Public Suffix List: example.co.uk for a.example.co.uk, ibm.de for
+// a.ibm.de, a.github.io for a.github.io. IP addresses and hosts without a
+// registrable domain (localhost, public suffixes) are returned as is.
+func registrableDomain(host string) string {
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
 func isDigit(s string) bool {
 	if s == "" {
 		return false
@@ -692,7 +1247,7 @@ func normalizeFilterName(name string) string {
 
 func isValidFilter(name string) bool {
 	switch name {
-	case "hasext", "noext", "hasparams", "noparams", "whitelist", "blacklist", "removecontent", "vuln":
+	case "hasext", "noext", "hasparams", "noparams", "whitelist", "blacklist", "removecontent", "vuln", "hpp":
 		return true
 	default:
 		return false
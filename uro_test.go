@@ -0,0 +1,56 @@
+package uro
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCountMatchesResults(t *testing.T) {
+	p := NewProcessor(nil)
+	p.Process("https://a.com/x")
+	p.Process("https://a.com/x?id=1")
+	if got, want := p.Count(), len(p.Results()); got != want || got != 1 {
+		t.Errorf("Count() = %d, len(Results()) = %d, want 1", got, want)
+	}
+}
+
+// The stream callback may call back into the processor.
+func TestStreamOutputCallsProcessor(t *testing.T) {
+	var p *Processor
+	p = NewProcessor(&Options{
+		Workers: 4,
+		StreamOutput: func(string) {
+			_ = p.Count()
+			_ = p.Stats()
+		},
+	})
+	p.ProcessReader(strings.NewReader("https://a.com/x\nhttps://b.com/y\nhttps://c.com/z\n"))
+	if got := p.Count(); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+}
+
+// With host-scoped patterns, parallel mode keeps the same URLs as a
+// sequential run, in the same order.
+func TestParallelMatchesSequential(t *testing.T) {
+	var b strings.Builder
+	for i := range 2000 {
+		fmt.Fprintf(&b, "https://h%d.com/users/%d?p%d=1\n", i%7, i%13, i%5)
+		fmt.Fprintf(&b, "https://h%d.com/item/%d/edit?id=%d&x%d=2\n", i%11, i, i%3, i%4)
+	}
+	input := b.String()
+
+	opts := Options{PatternScope: ScopeHost, ParamScope: ScopeHost, Filters: []string{"keepcontent"}}
+	seq := NewProcessor(&opts)
+	seq.ProcessReader(strings.NewReader(input))
+
+	opts.Workers = 4
+	par := NewProcessor(&opts)
+	par.ProcessReader(strings.NewReader(input))
+
+	if got, want := par.Results(), seq.Results(); !slices.Equal(got, want) {
+		t.Errorf("parallel kept %d URLs, sequential %d", len(got), len(want))
+	}
+}
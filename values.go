@@ -0,0 +1,101 @@
+package uro
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Parameter value classes used by value-aware deduplication.
+const (
+	ValueEmpty  = "empty"
+	ValueInt    = "int"
+	ValueFloat  = "float"
+	ValueBool   = "bool"
+	ValueURL    = "url"
+	ValuePath   = "path"
+	ValueEmail  = "email"
+	ValueJSON   = "json"
+	ValueBase64 = "base64"
+	ValueText   = "text"
+)
+
+var (
+	reValueInt    = regexp.MustCompile(`^[+-]?\d+$`)
+	reValueFloat  = regexp.MustCompile(`^[+-]?(\d+\.\d*|\.\d+|\d+)([eE][+-]?\d+)?$`)
+	reValueURL    = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*:)?//`)
+	reValueEmail  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[a-zA-Z]{2,}$`)
+	reValueBase64 = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+)
+
+// ClassifyValue returns the type class of a query parameter value:
+// one of ValueEmpty, ValueInt, ValueFloat, ValueBool, ValueURL, ValuePath,
+// ValueEmail, ValueJSON, ValueBase64 or ValueText.
+// The value may be percent-encoded.
+func ClassifyValue(value string) string {
+	if decoded, err := url.QueryUnescape(value); err == nil {
+		value = decoded
+	}
+	value = strings.TrimSpace(value)
+
+	switch {
+	case value == "":
+		return ValueEmpty
+	case reValueInt.MatchString(value):
+		return ValueInt
+	case reValueFloat.MatchString(value):
+		return ValueFloat
+	case isBoolValue(value):
+		return ValueBool
+	case reValueURL.MatchString(value):
+		return ValueURL
+	case reValueEmail.MatchString(value):
+		return ValueEmail
+	case isPathValue(value):
+		return ValuePath
+	case (value[0] == '{' || value[0] == '[') && json.Valid([]byte(value)):
+		return ValueJSON
+	case isBase64Value(value):
+		return ValueBase64
+	default:
+		return ValueText
+	}
+}
+
+func isBoolValue(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off":
+		return true
+	default:
+		return false
+	}
+}
+
+func isPathValue(s string) bool {
+	if strings.ContainsAny(s, " \t") {
+		return false
+	}
+	return strings.HasPrefix(s, "/") || strings.HasPrefix(s, "./") ||
+		strings.HasPrefix(s, "../") || strings.HasPrefix(s, "~/") ||
+		strings.Contains(s, "\\")
+}
+
+// isBase64Value reports whether s looks like base64 data rather than a word:
+// at least 12 chars, decodable, and containing a digit or base64-only symbols.
+func isBase64Value(s string) bool {
+	if len(s) < 12 || !reValueBase64.MatchString(s) {
+		return false
+	}
+	if !strings.ContainsAny(s, "0123456789+/=_-") {
+		return false
+	}
+	raw := strings.TrimRight(s, "=")
+	if strings.ContainsAny(raw, "-_") {
+		_, err := base64.RawURLEncoding.DecodeString(raw)
+		return err == nil
+	}
+	_, err := base64.RawStdEncoding.DecodeString(raw)
+	return err == nil
+}